@@ -20,10 +20,111 @@ package conf
 import "perf-storage-go/util"
 
 var (
-	MinioEndpoint     = util.GetEnvStr("MINIO_ENDPOINT", "localhost:9000")
-	MinioUsername     = util.GetEnvStr("MINIO_USERNAME", "admin")
-	MinioPassword     = util.GetEnvStr("MINIO_PASSWORD", "password")
-	MinioBucketName   = util.GetEnvStr("MINIO_BUCKET_NAME", "perf-bucket")
-	MinioStorageClass = util.GetEnvStr("MINIO_STORAGE_CLASS", "")
-	MinioDisableMultipart = util.GetEnvBool("MINIO_DISABLE_MULTIPART", false)
+	MinioEndpoint           = util.GetEnvStr("MINIO_ENDPOINT", "localhost:9000")
+	MinioUsername           = util.GetEnvStr("MINIO_USERNAME", "admin")
+	MinioPassword           = util.GetEnvStr("MINIO_PASSWORD", "password")
+	MinioBucketName         = util.GetEnvStr("MINIO_BUCKET_NAME", "perf-bucket")
+	MinioStorageClass       = util.GetEnvStr("MINIO_STORAGE_CLASS", "")
+	MinioDisableMultipart   = util.GetEnvBool("MINIO_DISABLE_MULTIPART", false)
+	MinioReadVerifySize     = util.GetEnvBool("MINIO_READ_VERIFY_SIZE", false)
+	MinioPartSize           = util.GetEnvInt64("MINIO_PART_SIZE", 0)
+	MinioPartThreads        = util.GetEnvInt("MINIO_PART_THREADS", 0)
+	MinioMultipartThreshold = util.GetEnvInt64("MINIO_MULTIPART_THRESHOLD", 0)
+	MinioRawMultipart       = util.GetEnvBool("MINIO_RAW_MULTIPART", false)
+	MinioRangeReadOpPercent = util.GetEnvFloat64("MINIO_RANGE_READ_OP_PERCENT", 0)
+	MinioRangeSize          = util.GetEnvInt64("MINIO_RANGE_SIZE", 1024*1024)
+	MinioRangeOffset        = util.GetEnvStr("MINIO_RANGE_OFFSET", RangeOffsetRandom)
+	MinioSecure             = util.GetEnvBool("MINIO_SECURE", false)
+	MinioCaFile             = util.GetEnvStr("MINIO_CA_FILE", "")
+	MinioInsecureSkipVerify = util.GetEnvBool("MINIO_INSECURE_SKIP_VERIFY", false)
+	MinioRegion             = util.GetEnvStr("MINIO_REGION", "")
+	MinioBucketLookup       = util.GetEnvStr("MINIO_BUCKET_LOOKUP", BucketLookupAuto)
+	MinioSessionToken       = util.GetEnvStr("MINIO_SESSION_TOKEN", "")
+	MinioCredentials        = util.GetEnvStr("MINIO_CREDENTIALS", CredentialsStatic)
+	MinioCredentialsFile    = util.GetEnvStr("MINIO_CREDENTIALS_FILE", "")
+	MinioCredentialsProfile = util.GetEnvStr("MINIO_CREDENTIALS_PROFILE", "")
+	MinioMaxIdleConns       = util.GetEnvInt("MINIO_MAX_IDLE_CONNS", 0)
+	MinioMaxIdleConnsHost   = util.GetEnvInt("MINIO_MAX_IDLE_CONNS_PER_HOST", 0)
+	MinioIdleConnTimeout    = util.GetEnvInt("MINIO_IDLE_CONN_TIMEOUT_SECONDS", 0)
+	MinioDialTimeout        = util.GetEnvInt("MINIO_DIAL_TIMEOUT_SECONDS", 0)
+	MinioTlsTimeout         = util.GetEnvInt("MINIO_TLS_HANDSHAKE_TIMEOUT_SECONDS", 0)
+	MinioHeaderTimeout      = util.GetEnvInt("MINIO_RESPONSE_HEADER_TIMEOUT_SECONDS", 0)
+	MinioDownloadFsync      = util.GetEnvBool("MINIO_DOWNLOAD_FSYNC", false)
+	MinioVerify             = util.GetEnvBool("MINIO_VERIFY", false)
+	MinioChecksum           = util.GetEnvStr("MINIO_CHECKSUM", ChecksumCrc32c)
+	MinioSendChecksum       = util.GetEnvBool("MINIO_SEND_CHECKSUM", false)
+	MinioOpWeights          = util.GetEnvStr("MINIO_OP_WEIGHTS", "")
+	MinioListPrefix         = util.GetEnvStr("MINIO_LIST_PREFIX", "")
+	MinioListDelimiter      = util.GetEnvStr("MINIO_LIST_DELIMITER", "")
+	MinioListPageSize       = util.GetEnvInt("MINIO_LIST_PAGE_SIZE", 1000)
+	MinioDeleteBatchSize    = util.GetEnvInt("MINIO_DELETE_BATCH_SIZE", 100)
+	MinioSse                = util.GetEnvStr("MINIO_SSE", SseNone)
+	MinioSseKmsKeyId        = util.GetEnvStr("MINIO_SSE_KMS_KEY_ID", "")
+	MinioSseCustomerKey     = util.GetEnvStr("MINIO_SSE_CUSTOMER_KEY", "")
+	MinioVersioning         = util.GetEnvBool("MINIO_VERSIONING", false)
+	MinioObjectLock         = util.GetEnvBool("MINIO_OBJECT_LOCK", false)
+	MinioLockMode           = util.GetEnvStr("MINIO_LOCK_MODE", "GOVERNANCE")
+	MinioLockRetentionSec   = util.GetEnvInt("MINIO_LOCK_RETENTION_SECONDS", 0)
+	MinioPresetVersions     = util.GetEnvInt("MINIO_PRESET_VERSIONS", 0)
+	MinioVersionHistory     = util.GetEnvInt("MINIO_VERSION_HISTORY", 16)
+	MinioBucketNum          = util.GetEnvInt("MINIO_BUCKET_NUM", 1)
+	MinioBucketTemplate     = util.GetEnvStr("MINIO_BUCKET_TEMPLATE", "%s-%d")
+	MinioEndpointMode       = util.GetEnvStr("MINIO_ENDPOINT_MODE", EndpointPerWorker)
+	// every compose source but the last must be 5MiB or more, so COMPOSE needs a DATA_SIZE of 5MiB
+	MinioComposeSources  = util.GetEnvInt("MINIO_COMPOSE_SOURCES", 4)
+	MinioPresignExpiry   = util.GetEnvInt("MINIO_PRESIGN_EXPIRY_SECONDS", 3600)
+	MinioDiscoveryMode   = util.GetEnvStr("MINIO_DISCOVERY_MODE", DiscoveryFirst)
+	MinioDiscoveryPrefix = util.GetEnvStr("MINIO_DISCOVERY_PREFIX", "")
+	MinioKeyManifest     = util.GetEnvStr("MINIO_KEY_MANIFEST", "")
+	MinioHttpTrace       = util.GetEnvBool("MINIO_HTTP_TRACE", false)
+)
+
+const (
+	// RangeOffsetRandom reads a range at a random offset of a random object
+	RangeOffsetRandom = "RANDOM"
+	// RangeOffsetSequential scans an object range by range before moving to the next one
+	RangeOffsetSequential = "SEQUENTIAL"
+	// RangeOffsetTail reads the last range of a random object
+	RangeOffsetTail = "TAIL"
+)
+
+const (
+	// EndpointPerWorker spreads the workers across the endpoints of MINIO_ENDPOINT, every worker sticks to one
+	EndpointPerWorker = "PER_WORKER"
+	// EndpointRoundRobin sends the requests of every worker to the endpoints in turn
+	EndpointRoundRobin = "ROUND_ROBIN"
+)
+
+const (
+	// DiscoveryFirst uses the first DATA_SET_SIZE keys listed and stops listing
+	DiscoveryFirst = "FIRST"
+	// DiscoverySample lists the whole bucket and keeps a uniform sample of DATA_SET_SIZE keys
+	DiscoverySample = "SAMPLE"
+)
+
+const (
+	SseNone = "NONE"
+	SseS3   = "SSE-S3"
+	SseKms  = "SSE-KMS"
+	// SseC the 32 byte MINIO_SSE_CUSTOMER_KEY is sent with every request, the endpoint must use tls
+	SseC = "SSE-C"
+)
+
+const (
+	ChecksumMd5    = "MD5"
+	ChecksumCrc32c = "CRC32C"
+	ChecksumSha256 = "SHA256"
+)
+
+const (
+	BucketLookupAuto = "AUTO"
+	BucketLookupPath = "PATH"
+	BucketLookupDns  = "DNS"
+)
+
+const (
+	// CredentialsStatic uses MINIO_USERNAME, MINIO_PASSWORD and MINIO_SESSION_TOKEN
+	CredentialsStatic = "STATIC"
+	// CredentialsChain tries the aws and minio env variables, the aws and minio client credential files and IAM in turn
+	CredentialsChain = "CHAIN"
 )
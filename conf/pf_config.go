@@ -23,30 +23,52 @@ import (
 )
 
 var (
-	StorageType        = os.Getenv("STORAGE_TYPE")
-	ExchangeType       = os.Getenv("EXCHANGE_TYPE")
-	PresetRoutineNum   = util.GetEnvInt("PRESET_ROUTINE_NUM", 100)
-	RoutineNum         = util.GetEnvInt("ROUTINE_NUM", 100)
-	RoutineRateLimit   = util.GetEnvInt("ROUTINE_RATE_LIMIT", 100)
-	UpdateRateInterval = util.GetEnvInt("UPDATE_RATE_INTERVAL_SECONDS", 0)
-	ReadRateInterval   = util.GetEnvInt("READ_RATE_INTERVAL_SECONDS", 0)
-	DataSize           = util.GetEnvInt64("DATA_SIZE", 10240)
-	RandomDataEnable   = util.GetEnvBool("RANDOM_DATA_ENABLE", false)
-	DataSetSize        = util.GetEnvInt("DATA_SET_SIZE", 100_000)
-	ReadOpPercent      = util.GetEnvFloat64("READ_OP_PERCENT", 0.25)
-	UpdateOpPercent    = util.GetEnvFloat64("UPDATE_OP_PERCENT", 0.75)
+	StorageType         = os.Getenv("STORAGE_TYPE")
+	ExchangeType        = os.Getenv("EXCHANGE_TYPE")
+	PresetRoutineNum    = util.GetEnvInt("PRESET_ROUTINE_NUM", 100)
+	RoutineNum          = util.GetEnvInt("ROUTINE_NUM", 100)
+	RoutineRateLimit    = util.GetEnvInt("ROUTINE_RATE_LIMIT", 100)
+	UpdateRateInterval  = util.GetEnvInt("UPDATE_RATE_INTERVAL_SECONDS", 0)
+	ReadRateInterval    = util.GetEnvInt("READ_RATE_INTERVAL_SECONDS", 0)
+	DataSize            = util.GetEnvInt64("DATA_SIZE", 10240)
+	RandomDataEnable    = util.GetEnvBool("RANDOM_DATA_ENABLE", false)
+	DataCompressible    = util.GetEnvBool("DATA_COMPRESSIBLE", false)
+	DataFileSparse      = util.GetEnvBool("DATA_FILE_SPARSE", false)
+	DataFilePreallocate = util.GetEnvBool("DATA_FILE_PREALLOCATE", false)
+	DataFileDir         = util.GetEnvStr("DATA_FILE_DIR", "/opt/perf/testdata")
+	DataSetSize         = util.GetEnvInt("DATA_SET_SIZE", 100_000)
+	ReadOpPercent       = util.GetEnvFloat64("READ_OP_PERCENT", 0.25)
+	UpdateOpPercent     = util.GetEnvFloat64("UPDATE_OP_PERCENT", 0.75)
 )
 
 const (
-	StorageTypeEtcd      = "ETCD"
-	StorageTypeMinio     = "MINIO"
-	StorageTypeMysql     = "MYSQL"
-	StorageTypeRedis     = "REDIS"
-	StorageTypeZooKeeper = "ZOOKEEPER"
-	ExchangeTypeMemory   = "MEMORY"
-	ExchangeTypeFile     = "FILE"
-	OperationTypeInsert  = "INSERT"
-	OperationTypeDelete  = "DELETE"
-	OperationTypeUpdate  = "UPDATE"
-	OperationTypeREAD    = "READ"
+	StorageTypeEtcd          = "ETCD"
+	StorageTypeMinio         = "MINIO"
+	StorageTypeMysql         = "MYSQL"
+	StorageTypeRedis         = "REDIS"
+	StorageTypeZooKeeper     = "ZOOKEEPER"
+	ExchangeTypeMemory       = "MEMORY"
+	ExchangeTypeFile         = "FILE"
+	OperationTypeInsert      = "INSERT"
+	OperationTypeDelete      = "DELETE"
+	OperationTypeUpdate      = "UPDATE"
+	OperationTypeREAD        = "READ"
+	OperationTypePublish     = "PUBLISH"
+	OperationTypeReceive     = "RECEIVE"
+	OperationTypeEvalSha     = "EVALSHA"
+	OperationTypeTx          = "TRANSACTION"
+	OperationTypeRangeRead   = "RANGE_READ"
+	OperationTypeList        = "LIST"
+	OperationTypeStat        = "STAT"
+	OperationTypePutTagging  = "PUT_TAGGING"
+	OperationTypeGetTagging  = "GET_TAGGING"
+	OperationTypePutMetadata = "PUT_METADATA"
+	OperationTypeBatchDelete = "BATCH_DELETE"
+	OperationTypeGetVersion  = "GET_VERSION"
+	OperationTypeListVersion = "LIST_VERSIONS"
+	OperationTypeDelMarker   = "DELETE_MARKER"
+	OperationTypeCopy        = "COPY"
+	OperationTypeCompose     = "COMPOSE"
+	OperationTypePresignGet  = "PRESIGNED_GET"
+	OperationTypePresignPut  = "PRESIGNED_PUT"
 )
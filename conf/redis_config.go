@@ -33,4 +33,46 @@ var (
 	RedisMinIdleConn       = util.GetEnvInt("REDIS_MIN_IDLE_CONN", 5)
 	RedisMaxIdleConn       = util.GetEnvInt("REDIS_MAX_IDLE_CONN", 10)
 	RedisExpirationSeconds = util.GetEnvInt("REDIS_EXPIRATION_SECONDS", 21600)
+	RedisPoolStatsInterval = util.GetEnvInt("REDIS_POOL_STATS_INTERVAL_SECONDS", 5)
+	RedisMode              = util.GetEnvStr("REDIS_MODE", RedisModeKv)
+	RedisPublisherNum      = util.GetEnvInt("REDIS_PUBLISHER_NUM", 10)
+	RedisSubscriberNum     = util.GetEnvInt("REDIS_SUBSCRIBER_NUM", 10)
+	RedisChannelNum        = util.GetEnvInt("REDIS_CHANNEL_NUM", 1)
+	RedisChannelPrefix     = util.GetEnvStr("REDIS_CHANNEL_PREFIX", "perf")
+	RedisScript            = util.GetEnvStr("REDIS_SCRIPT", defaultRedisScript)
+	RedisScriptFile        = util.GetEnvStr("REDIS_SCRIPT_FILE", "")
+	RedisTxOpPercent       = util.GetEnvFloat64("REDIS_TX_OP_PERCENT", 0.5)
+	RedisTxMaxRetries      = util.GetEnvInt("REDIS_TX_MAX_RETRIES", 0)
+	RedisTtlMode           = util.GetEnvStr("REDIS_TTL_MODE", RedisTtlModeNone)
+	RedisTtlMinSeconds     = util.GetEnvInt("REDIS_TTL_MIN_SECONDS", 60)
+	RedisTtlMaxSeconds     = util.GetEnvInt("REDIS_TTL_MAX_SECONDS", 21600)
+	RedisInsertTtlSeconds  = util.GetEnvInt("REDIS_INSERT_TTL_SECONDS", 0)
+	RedisUpdateTtlSeconds  = util.GetEnvInt("REDIS_UPDATE_TTL_SECONDS", 21600)
+	RedisEvictionKeyPrefix = util.GetEnvStr("REDIS_EVICTION_KEY_PREFIX", "perf-evict-")
+	RedisHitRatioInterval  = util.GetEnvInt("REDIS_HIT_RATIO_INTERVAL_SECONDS", 10)
+	RedisPresetPipeline    = util.GetEnvInt("REDIS_PRESET_PIPELINE_SIZE", 1)
+	RedisPresetLogInterval = util.GetEnvInt("REDIS_PRESET_LOG_INTERVAL_SECONDS", 10)
+)
+
+// defaultRedisScript reads the key and replaces it with ARGV[1]
+const defaultRedisScript = `local old = redis.call('GET', KEYS[1])
+redis.call('SET', KEYS[1], ARGV[1])
+return old`
+
+const (
+	RedisModeKv       = "KV"
+	RedisModePubSub   = "PUBSUB"
+	RedisModeScript   = "SCRIPT"
+	RedisModeEviction = "EVICTION"
+)
+
+const (
+	// RedisTtlModeNone keys never expire
+	RedisTtlModeNone = "NONE"
+	// RedisTtlModeFixed every write uses REDIS_EXPIRATION_SECONDS
+	RedisTtlModeFixed = "FIXED"
+	// RedisTtlModeRandom every write picks a ttl between REDIS_TTL_MIN_SECONDS and REDIS_TTL_MAX_SECONDS
+	RedisTtlModeRandom = "RANDOM"
+	// RedisTtlModePerOp inserts use REDIS_INSERT_TTL_SECONDS, other writes use REDIS_UPDATE_TTL_SECONDS
+	RedisTtlModePerOp = "PER_OP"
 )
@@ -24,4 +24,35 @@ var (
 	ZkPort       = util.GetEnvInt("ZK_PORT", 2181)
 	ZkPath       = util.GetEnvStr("ZK_PATH", "/perf")
 	ZkPermission = util.GetEnvInt("ZK_PERMISSION", 31)
+	ZkMode       = util.GetEnvStr("ZK_MODE", ZkModePreset)
+	ZkWatcherNum = util.GetEnvInt("ZK_WATCHER_NUM", 4)
+	ZkWriterNum  = util.GetEnvInt("ZK_WRITER_NUM", 1)
+	ZkWatchNodes = util.GetEnvInt("ZK_WATCH_NODE_NUM", 16)
+	ZkWatchType  = util.GetEnvStr("ZK_WATCH_TYPE", ZkWatchBoth)
+	ZkNodeType   = util.GetEnvStr("ZK_CHURN_NODE_TYPE", ZkNodeEphemeralSequential)
+	ZkHoldNodes  = util.GetEnvInt("ZK_CHURN_HOLD_NODES", 100)
+	ZkSessionOps = util.GetEnvInt("ZK_CHURN_SESSION_OPS", 10000)
+)
+
+const (
+	// ZkModePreset only creates the data set
+	ZkModePreset = "PRESET"
+	// ZkModeWatch lets writers change nodes watched by the watchers
+	ZkModeWatch = "WATCH"
+	// ZkModeChurn creates and deletes nodes of ZK_CHURN_NODE_TYPE, every ZK_CHURN_SESSION_OPS
+	// operations the session is closed with ZK_CHURN_HOLD_NODES nodes left
+	ZkModeChurn = "CHURN"
+)
+
+const (
+	ZkNodePersistent          = "PERSISTENT"
+	ZkNodeEphemeral           = "EPHEMERAL"
+	ZkNodeSequential          = "SEQUENTIAL"
+	ZkNodeEphemeralSequential = "EPHEMERAL_SEQUENTIAL"
+)
+
+const (
+	ZkWatchData  = "DATA"
+	ZkWatchChild = "CHILD"
+	ZkWatchBoth  = "BOTH"
 )
@@ -30,6 +30,7 @@ import (
 	"perf-storage-go/mysql"
 	"perf-storage-go/redis"
 	"perf-storage-go/zookeeper"
+	"syscall"
 
 	_ "net/http/pprof"
 )
@@ -62,8 +63,10 @@ func main() {
 		panic(err)
 	}
 	interrupt := make(chan os.Signal, 1)
-	signal.Notify(interrupt, os.Interrupt)
-	for {
-		<-interrupt
+	signal.Notify(interrupt, os.Interrupt, syscall.SIGTERM)
+	<-interrupt
+	logrus.Info("perf storage stop")
+	if conf.StorageType == conf.StorageTypeMinio {
+		minio.Stop()
 	}
 }
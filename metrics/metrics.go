@@ -39,4 +39,9 @@ var (
 			Objectives: map[float64]float64{0.5: 0.05, 0.9: 0.01, 0.99: 0.001}},
 		[]string{"storage_type", "operation_type"},
 	)
+	PresetProgress = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: prometheus.BuildFQName(namespace, "", "preset_progress_ratio")},
+		[]string{"storage_type"},
+	)
 )
@@ -0,0 +1,155 @@
+// Licensed to the Apache Software Foundation (ASF) under one
+// or more contributor license agreements.  See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership.  The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// the transfer metrics are labelled with MINIO_SSE, so the cost of the encryption modes can be compared
+var (
+	MinioFirstByteLatency = promauto.NewSummaryVec(
+		prometheus.SummaryOpts{
+			Name:       prometheus.BuildFQName(namespace, "minio", "first_byte_latency_summary"),
+			Objectives: map[float64]float64{0.5: 0.05, 0.9: 0.01, 0.99: 0.001}},
+		[]string{"operation_type", "sse"},
+	)
+	MinioTransferLatency = promauto.NewSummaryVec(
+		prometheus.SummaryOpts{
+			Name:       prometheus.BuildFQName(namespace, "minio", "transfer_latency_summary"),
+			Objectives: map[float64]float64{0.5: 0.05, 0.9: 0.01, 0.99: 0.001}},
+		[]string{"operation_type", "sse"},
+	)
+	MinioDiskWriteLatency = promauto.NewSummaryVec(
+		prometheus.SummaryOpts{
+			Name:       prometheus.BuildFQName(namespace, "minio", "disk_write_latency_summary"),
+			Objectives: map[float64]float64{0.5: 0.05, 0.9: 0.01, 0.99: 0.001}},
+		[]string{"operation_type"},
+	)
+	MinioBytes = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: prometheus.BuildFQName(namespace, "minio", "bytes_total")},
+		[]string{"operation_type", "sse"},
+	)
+)
+
+var (
+	MinioPartLatency = promauto.NewSummary(
+		prometheus.SummaryOpts{
+			Name:       prometheus.BuildFQName(namespace, "minio", "part_latency_summary"),
+			Objectives: map[float64]float64{0.5: 0.05, 0.9: 0.01, 0.99: 0.001}},
+	)
+	MinioPartFailures = promauto.NewCounter(
+		prometheus.CounterOpts{
+			Name: prometheus.BuildFQName(namespace, "minio", "part_failures_total")},
+	)
+	MinioMultipartAborts = promauto.NewCounter(
+		prometheus.CounterOpts{
+			Name: prometheus.BuildFQName(namespace, "minio", "multipart_aborts_total")},
+	)
+)
+
+var (
+	MinioVerifyMismatches = promauto.NewCounter(
+		prometheus.CounterOpts{
+			Name: prometheus.BuildFQName(namespace, "minio", "verify_mismatches_total")},
+	)
+)
+
+var (
+	MinioListedObjects = promauto.NewCounter(
+		prometheus.CounterOpts{
+			Name: prometheus.BuildFQName(namespace, "minio", "listed_objects_total")},
+	)
+)
+
+var (
+	MinioListedVersions = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: prometheus.BuildFQName(namespace, "minio", "listed_versions_total")},
+		[]string{"type"},
+	)
+)
+
+var (
+	MinioBatchDeleteObjects = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: prometheus.BuildFQName(namespace, "minio", "batch_delete_objects_total")},
+		[]string{"result"},
+	)
+)
+
+var (
+	MinioCopyBytes = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: prometheus.BuildFQName(namespace, "minio", "copy_bytes_total")},
+		[]string{"operation_type", "sse"},
+	)
+)
+
+var (
+	MinioSignLatency = promauto.NewSummaryVec(
+		prometheus.SummaryOpts{
+			Name:       prometheus.BuildFQName(namespace, "minio", "sign_latency_summary"),
+			Objectives: map[float64]float64{0.5: 0.05, 0.9: 0.01, 0.99: 0.001}},
+		[]string{"operation_type"},
+	)
+)
+
+// the http phases of the minio requests in milliseconds, see MINIO_HTTP_TRACE
+var (
+	httpPhaseBuckets = prometheus.ExponentialBuckets(0.05, 2, 18)
+
+	MinioDnsLatency = promauto.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    prometheus.BuildFQName(namespace, "minio", "http_dns_latency"),
+			Buckets: httpPhaseBuckets},
+		[]string{"operation_type"},
+	)
+	MinioConnectLatency = promauto.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    prometheus.BuildFQName(namespace, "minio", "http_connect_latency"),
+			Buckets: httpPhaseBuckets},
+		[]string{"operation_type"},
+	)
+	MinioTlsLatency = promauto.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    prometheus.BuildFQName(namespace, "minio", "http_tls_latency"),
+			Buckets: httpPhaseBuckets},
+		[]string{"operation_type"},
+	)
+	MinioRequestWriteLatency = promauto.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    prometheus.BuildFQName(namespace, "minio", "http_request_write_latency"),
+			Buckets: httpPhaseBuckets},
+		[]string{"operation_type"},
+	)
+	MinioTtfbLatency = promauto.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    prometheus.BuildFQName(namespace, "minio", "http_ttfb_latency"),
+			Buckets: httpPhaseBuckets},
+		[]string{"operation_type"},
+	)
+	MinioBodyLatency = promauto.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    prometheus.BuildFQName(namespace, "minio", "http_body_latency"),
+			Buckets: httpPhaseBuckets},
+		[]string{"operation_type"},
+	)
+)
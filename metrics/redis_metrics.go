@@ -0,0 +1,104 @@
+// Licensed to the Apache Software Foundation (ASF) under one
+// or more contributor license agreements.  See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership.  The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var (
+	RedisPoolHits = promauto.NewCounter(
+		prometheus.CounterOpts{
+			Name: prometheus.BuildFQName(namespace, "redis", "pool_hits_total")},
+	)
+	RedisPoolMisses = promauto.NewCounter(
+		prometheus.CounterOpts{
+			Name: prometheus.BuildFQName(namespace, "redis", "pool_misses_total")},
+	)
+	RedisPoolTimeouts = promauto.NewCounter(
+		prometheus.CounterOpts{
+			Name: prometheus.BuildFQName(namespace, "redis", "pool_timeouts_total")},
+	)
+	RedisPoolTotalConns = promauto.NewGauge(
+		prometheus.GaugeOpts{
+			Name: prometheus.BuildFQName(namespace, "redis", "pool_total_conns")},
+	)
+	RedisPoolIdleConns = promauto.NewGauge(
+		prometheus.GaugeOpts{
+			Name: prometheus.BuildFQName(namespace, "redis", "pool_idle_conns")},
+	)
+	RedisPoolStaleConns = promauto.NewCounter(
+		prometheus.CounterOpts{
+			Name: prometheus.BuildFQName(namespace, "redis", "pool_stale_conns_total")},
+	)
+	RedisCommandLatency = promauto.NewSummaryVec(
+		prometheus.SummaryOpts{
+			Name:       prometheus.BuildFQName(namespace, "redis", "command_latency_summary"),
+			Objectives: map[float64]float64{0.5: 0.05, 0.9: 0.01, 0.99: 0.001}},
+		[]string{"command"},
+	)
+	RedisCommandErrors = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: prometheus.BuildFQName(namespace, "redis", "command_errors_total")},
+		[]string{"command", "error_type"},
+	)
+)
+
+var (
+	RedisDeliveryLatency = promauto.NewSummary(
+		prometheus.SummaryOpts{
+			Name:       prometheus.BuildFQName(namespace, "redis", "delivery_latency_summary"),
+			Objectives: map[float64]float64{0.5: 0.05, 0.9: 0.01, 0.99: 0.001}},
+	)
+	RedisLostMessages = promauto.NewCounter(
+		prometheus.CounterOpts{
+			Name: prometheus.BuildFQName(namespace, "redis", "lost_messages_total")},
+	)
+	RedisMessageBytes = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: prometheus.BuildFQName(namespace, "redis", "message_bytes_total")},
+		[]string{"operation_type"},
+	)
+)
+
+var (
+	RedisTxConflicts = promauto.NewCounter(
+		prometheus.CounterOpts{
+			Name: prometheus.BuildFQName(namespace, "redis", "tx_conflicts_total")},
+	)
+	RedisTxAborts = promauto.NewCounter(
+		prometheus.CounterOpts{
+			Name: prometheus.BuildFQName(namespace, "redis", "tx_aborts_total")},
+	)
+)
+
+var (
+	RedisCacheHits = promauto.NewCounter(
+		prometheus.CounterOpts{
+			Name: prometheus.BuildFQName(namespace, "redis", "cache_hits_total")},
+	)
+	RedisCacheMisses = promauto.NewCounter(
+		prometheus.CounterOpts{
+			Name: prometheus.BuildFQName(namespace, "redis", "cache_misses_total")},
+	)
+	RedisHitRatio = promauto.NewGauge(
+		prometheus.GaugeOpts{
+			Name: prometheus.BuildFQName(namespace, "redis", "hit_ratio")},
+	)
+)
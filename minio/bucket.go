@@ -0,0 +1,75 @@
+// Licensed to the Apache Software Foundation (ASF) under one
+// or more contributor license agreements.  See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership.  The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package minio
+
+import (
+	"fmt"
+	"hash/fnv"
+	"perf-storage-go/conf"
+	"strings"
+)
+
+// buckets are the buckets the keys are spread across
+var buckets = bucketNames(conf.MinioBucketName, conf.MinioBucketNum, conf.MinioBucketTemplate)
+
+// bucketNames keeps the bucket name as is for a single bucket, more buckets are named by
+// formatting the template with the bucket name and the bucket index
+func bucketNames(name string, num int, template string) []string {
+	if num <= 1 {
+		return []string{name}
+	}
+	names := make([]string, num)
+	for i := range names {
+		names[i] = fmt.Sprintf(template, name, i)
+	}
+	return names
+}
+
+// bucketOf places a key by its hash, so the bucket never has to be stored next to the key
+func bucketOf(key string) string {
+	if len(buckets) == 1 {
+		return buckets[0]
+	}
+	h := fnv.New32a()
+	h.Write([]byte(key))
+	return buckets[h.Sum32()%uint32(len(buckets))]
+}
+
+func objectPath(key string) string {
+	return bucketOf(key) + "/" + key
+}
+
+func groupByBucket(keys []string) map[string][]string {
+	groups := make(map[string][]string)
+	for _, key := range keys {
+		bucket := bucketOf(key)
+		groups[bucket] = append(groups[bucket], key)
+	}
+	return groups
+}
+
+// endpoints splits the comma separated MINIO_ENDPOINT
+func endpoints() []string {
+	var list []string
+	for _, endpoint := range strings.Split(conf.MinioEndpoint, ",") {
+		if endpoint = strings.TrimSpace(endpoint); endpoint != "" {
+			list = append(list, endpoint)
+		}
+	}
+	return list
+}
@@ -0,0 +1,58 @@
+// Licensed to the Apache Software Foundation (ASF) under one
+// or more contributor license agreements.  See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership.  The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package minio
+
+import (
+	"github.com/stretchr/testify/assert"
+	"perf-storage-go/conf"
+	"testing"
+)
+
+func TestBucketNames(t *testing.T) {
+	assert.Equal(t, []string{"perf"}, bucketNames("perf", 1, "%s-%d"))
+	assert.Equal(t, []string{"perf-0", "perf-1", "perf-2"}, bucketNames("perf", 3, "%s-%d"))
+}
+
+func TestBucketOf(t *testing.T) {
+	names := buckets
+	defer func() {
+		buckets = names
+	}()
+	buckets = bucketNames("perf", 4, "%s-%d")
+	keys := []string{"a", "b", "c", "d", "e", "f", "g", "h"}
+	groups := groupByBucket(keys)
+	var total int
+	for bucket, bucketKeys := range groups {
+		assert.Contains(t, buckets, bucket)
+		for _, key := range bucketKeys {
+			assert.Equal(t, bucket, bucketOf(key))
+			assert.Equal(t, bucket+"/"+key, objectPath(key))
+		}
+		total += len(bucketKeys)
+	}
+	assert.Equal(t, len(keys), total)
+}
+
+func TestEndpoints(t *testing.T) {
+	endpoint := conf.MinioEndpoint
+	defer func() {
+		conf.MinioEndpoint = endpoint
+	}()
+	conf.MinioEndpoint = "node1:9000, node2:9000,"
+	assert.Equal(t, []string{"node1:9000", "node2:9000"}, endpoints())
+}
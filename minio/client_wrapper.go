@@ -18,23 +18,44 @@
 package minio
 
 import (
-	"bytes"
 	"context"
+	"encoding/xml"
 	"fmt"
+	"github.com/google/uuid"
 	"github.com/minio/minio-go/v7"
-	"github.com/minio/minio-go/v7/pkg/credentials"
+	"github.com/minio/minio-go/v7/pkg/encrypt"
+	"github.com/minio/minio-go/v7/pkg/tags"
 	"github.com/sirupsen/logrus"
+	"io"
+	"math/rand"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
 	"perf-storage-go/conf"
 	"perf-storage-go/util"
+	"strconv"
+	"sync"
+	"time"
 )
 
-const FixedFileDir = "/opt/perf/testdata/"
+var (
+	clis     []*Cli
+	clisLock sync.Mutex
+)
 
 type Cli struct {
-	client     *minio.Client
-	dataSize   int64
-	bufferType string
-	filename   string
+	client       *minio.Client
+	dataSize     int64
+	bufferType   string
+	filename     string
+	downloadPath string
+	sse          encrypt.ServerSide
+	// httpClient sends the requests of presigned urls
+	httpClient *http.Client
+	tracer     *httpTracer
+	fileSpec   payloadSpec
+	seed       int64
 }
 
 func (c Cli) BucketExists(ctx context.Context, name string) (bool, error) {
@@ -49,72 +70,424 @@ func (c Cli) ListObjects(ctx context.Context, name string, opts minio.ListObject
 	return c.client.ListObjects(ctx, name, opts)
 }
 
-func (c Cli) PutObject(ctx context.Context, name string, key string, dataSize int64) (minio.UploadInfo, error) {
-	opts := minio.PutObjectOptions{DisableMultipart: conf.MinioDisableMultipart}
-	if conf.MinioStorageClass != "" {
-		opts.StorageClass = conf.MinioStorageClass
+func (c Cli) StatObject(ctx context.Context, name string, key string) (minio.ObjectInfo, error) {
+	return c.client.StatObject(ctx, name, key, minio.StatObjectOptions{ServerSideEncryption: readSse(c.sse)})
+}
+
+// ListObjectsPage sends a single ListObjectsV2 request, an empty continuation token starts from the beginning.
+// minio-go lists without the context of the caller, so the request is signed and sent here with ctx
+func (c Cli) ListObjectsPage(ctx context.Context, name, prefix, delimiter, token string, pageSize int) (minio.ListBucketV2Result, error) {
+	params := make(url.Values)
+	params.Set("list-type", "2")
+	params.Set("prefix", prefix)
+	params.Set("delimiter", delimiter)
+	params.Set("max-keys", strconv.Itoa(pageSize))
+	if token != "" {
+		params.Set("continuation-token", token)
+	}
+	u, err := c.client.Presign(ctx, http.MethodGet, name, "", time.Second*time.Duration(conf.MinioPresignExpiry), params)
+	if err != nil {
+		return minio.ListBucketV2Result{}, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return minio.ListBucketV2Result{}, err
+	}
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return minio.ListBucketV2Result{}, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return minio.ListBucketV2Result{}, fmt.Errorf("list objects of bucket %s failed: %s", name, resp.Status)
+	}
+	var result minio.ListBucketV2Result
+	if err := xml.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return minio.ListBucketV2Result{}, fmt.Errorf("decode list objects of bucket %s failed: %w", name, err)
+	}
+	return result, nil
+}
+
+func (c Cli) PutObjectTagging(ctx context.Context, name string, key string, objectTags map[string]string) error {
+	t, err := tags.MapToObjectTags(objectTags)
+	if err != nil {
+		return err
+	}
+	return c.client.PutObjectTagging(ctx, name, key, t, minio.PutObjectTaggingOptions{})
+}
+
+func (c Cli) GetObjectTagging(ctx context.Context, name string, key string) (map[string]string, error) {
+	t, err := c.client.GetObjectTagging(ctx, name, key, minio.GetObjectTaggingOptions{})
+	if err != nil {
+		return nil, err
+	}
+	return t.ToMap(), nil
+}
+
+// ReplaceMetadata rewrites the user metadata of an object with a server side copy onto itself
+func (c Cli) ReplaceMetadata(ctx context.Context, name string, key string, metadata map[string]string) error {
+	_, err := c.client.CopyObject(ctx,
+		minio.CopyDestOptions{Bucket: name, Object: key, UserMetadata: metadata, ReplaceMetadata: true, Encryption: c.sse},
+		minio.CopySrcOptions{Bucket: name, Object: key, Encryption: readSse(c.sse)})
+	return err
+}
+
+// CopyObject copies an object on the server side, the user metadata is copied along
+func (c Cli) CopyObject(ctx context.Context, srcName string, srcKey string, dstName string, dstKey string) (minio.UploadInfo, error) {
+	return c.client.CopyObject(ctx,
+		minio.CopyDestOptions{Bucket: dstName, Object: dstKey, Encryption: c.sse},
+		minio.CopySrcOptions{Bucket: srcName, Object: srcKey, Encryption: readSse(c.sse)})
+}
+
+// ComposeObject concatenates the sources into one object on the server side, every source but the
+// last has to be at least 5MiB
+func (c Cli) ComposeObject(ctx context.Context, dstName string, dstKey string, srcs []minio.CopySrcOptions) (minio.UploadInfo, error) {
+	for i := range srcs {
+		srcs[i].Encryption = readSse(c.sse)
+	}
+	return c.client.ComposeObject(ctx, minio.CopyDestOptions{Bucket: dstName, Object: dstKey, Encryption: c.sse}, srcs...)
+}
+
+func (c Cli) RemoveObject(ctx context.Context, name string, key string) error {
+	return c.client.RemoveObject(ctx, name, key, minio.RemoveObjectOptions{})
+}
+
+func (c Cli) EnableVersioning(ctx context.Context, name string) error {
+	return c.client.EnableVersioning(ctx, name)
+}
+
+// ListVersions returns the ids of the versions of key, delete markers excluded
+func (c Cli) ListVersions(ctx context.Context, name string, key string) ([]string, error) {
+	var versionIds []string
+	for object := range c.client.ListObjects(ctx, name, minio.ListObjectsOptions{Prefix: key, WithVersions: true}) {
+		if object.Err != nil {
+			return nil, object.Err
+		}
+		if object.Key == key && !object.IsDeleteMarker {
+			versionIds = append(versionIds, object.VersionID)
+		}
 	}
+	return versionIds, nil
+}
+
+// ListVersionsPage reads up to pageSize versions and delete markers under prefix, it returns how
+// many entries were read and how many of them were delete markers
+func (c Cli) ListVersionsPage(ctx context.Context, name string, prefix string, pageSize int) (int, int, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	var listed, markers int
+	opts := minio.ListObjectsOptions{Prefix: prefix, WithVersions: true, Recursive: true, MaxKeys: pageSize}
+	for object := range c.client.ListObjects(ctx, name, opts) {
+		if object.Err != nil {
+			return listed, markers, object.Err
+		}
+		listed++
+		if object.IsDeleteMarker {
+			markers++
+		}
+		if listed >= pageSize {
+			break
+		}
+	}
+	return listed, markers, nil
+}
+
+// RemoveObjects deletes keys with multi object delete requests and returns the error of every key not deleted
+func (c Cli) RemoveObjects(ctx context.Context, name string, keys []string) map[string]error {
+	objectsCh := make(chan minio.ObjectInfo, len(keys))
+	for _, key := range keys {
+		objectsCh <- minio.ObjectInfo{Key: key}
+	}
+	close(objectsCh)
+	errs := make(map[string]error)
+	for removeErr := range c.client.RemoveObjects(ctx, name, objectsCh, minio.RemoveObjectsOptions{}) {
+		errs[removeErr.ObjectName] = removeErr.Err
+	}
+	return errs
+}
+
+// payload returns the content to upload and its size, release has to be called once the upload is done
+func (c Cli) payload(dataSize int64) (src io.ReaderAt, size int64, spec payloadSpec, release func() error, err error) {
 	switch c.bufferType {
 	case conf.ExchangeTypeFile:
-		return c.client.FPutObject(ctx, name, key, c.filename, opts)
+		file, err := os.Open(c.filename)
+		if err != nil {
+			return nil, 0, spec, nil, err
+		}
+		info, err := file.Stat()
+		if err != nil {
+			file.Close()
+			return nil, 0, spec, nil, err
+		}
+		return file, info.Size(), c.fileSpec, file.Close, nil
 	default:
-		var data []byte
+		seed := c.seed
 		if conf.RandomDataEnable {
-			data = util.RandBytes(dataSize)
-		} else {
-			data = FixedBytesCache
+			seed = rand.Int63()
 		}
-		return c.client.PutObject(ctx, name, key, bytes.NewReader(data), dataSize, opts)
+		spec = newPayloadSpec(seed, conf.DataCompressible, false)
+		return spec.reader(dataSize), dataSize, spec, func() error { return nil }, nil
 	}
 }
 
-func (c Cli) GetObject(ctx context.Context, name string, key string, opts minio.GetObjectOptions) error {
-	var err error
-	var object *minio.Object
-	switch conf.ExchangeType {
-	case conf.ExchangeTypeFile:
-		err = c.client.FGetObject(ctx, name, key, fmt.Sprintf("%s_download", c.filename), opts)
-		if err != nil {
-			logrus.Errorf("get file object failed: %v", err)
-			return err
+func (c Cli) PutObject(ctx context.Context, name string, key string, dataSize int64) (minio.UploadInfo, error) {
+	src, dataSize, spec, release, err := c.payload(dataSize)
+	if err != nil {
+		return minio.UploadInfo{}, err
+	}
+	defer release()
+	opts := putObjectOptions(dataSize)
+	opts.ServerSideEncryption = c.sse
+	setRetention(&opts)
+	if conf.MinioVerify {
+		opts.UserMetadata = spec.metadata()
+	}
+	if conf.MinioRawMultipart {
+		return c.putObjectMultipart(ctx, name, key, src, dataSize, opts)
+	}
+	if conf.MinioSendChecksum {
+		if err := setChecksum(&opts, src, dataSize); err != nil {
+			return minio.UploadInfo{}, err
 		}
+	}
+	return c.client.PutObject(ctx, name, key, io.NewSectionReader(src, 0, dataSize), dataSize, opts)
+}
+
+func putObjectOptions(dataSize int64) minio.PutObjectOptions {
+	opts := minio.PutObjectOptions{
+		DisableMultipart: conf.MinioDisableMultipart,
+		PartSize:         uint64(conf.MinioPartSize),
+		NumThreads:       uint(conf.MinioPartThreads),
+	}
+	if conf.MinioMultipartThreshold > 0 && dataSize < conf.MinioMultipartThreshold {
+		opts.DisableMultipart = true
+	}
+	if conf.MinioStorageClass != "" {
+		opts.StorageClass = conf.MinioStorageClass
+	}
+	return opts
+}
+
+// ReadResult describes how an object body was downloaded
+type ReadResult struct {
+	Bytes int64
+	// FirstByte is the time until the first byte of the body arrived, zero if unknown
+	FirstByte time.Duration
+	// Transfer is the time spent receiving the whole body, excluding DiskWrite
+	Transfer time.Duration
+	// DiskWrite is the time spent writing and syncing the body to the local file
+	DiskWrite time.Duration
+}
+
+func (c Cli) GetObject(ctx context.Context, name string, key string, opts minio.GetObjectOptions) (ReadResult, error) {
+	startTime := time.Now()
+	opts.ServerSideEncryption = readSse(c.sse)
+	object, err := c.client.GetObject(ctx, name, key, opts)
+	if err != nil {
+		logrus.Errorf("get object failed: %v", err)
+		return ReadResult{}, err
+	}
+	defer object.Close()
+	// only a full read can be compared with the regenerated payload
+	var verifier *payloadVerifier
+	var dst = io.Discard
+	if conf.MinioVerify && opts.Header().Get("Range") == "" {
+		verifier = newPayloadVerifier(key, object.Stat)
+		dst = verifier
+	}
+	var result ReadResult
+	switch c.bufferType {
+	case conf.ExchangeTypeFile:
+		result, err = c.download(object, verifier, startTime)
 	default:
-		object, err = c.client.GetObject(ctx, name, key, opts)
-		if err != nil {
-			logrus.Errorf("get memory object failed: %v", err)
-			return err
+		result, err = readBody(object, dst, startTime)
+	}
+	if err != nil {
+		logrus.Errorf("read object body failed: %v", err)
+		return result, err
+	}
+	if verifier != nil {
+		if err := verifier.verify(); err != nil {
+			return result, err
 		}
-		_, err := object.Stat()
+	}
+	// a ranged read returns only part of the object
+	if conf.MinioReadVerifySize && opts.Header().Get("Range") == "" {
+		info, err := object.Stat()
 		if err != nil {
 			logrus.Errorf("read object metadata failed: %v", err)
-			return err
+			return result, err
+		}
+		if info.Size != result.Bytes {
+			return result, fmt.Errorf("object %s size mismatch, expect %d, read %d", key, info.Size, result.Bytes)
+		}
+	}
+	return result, nil
+}
+
+// download writes body to the worker's own download file, timing the disk writes apart from the network
+func (c Cli) download(body io.Reader, verifier *payloadVerifier, startTime time.Time) (ReadResult, error) {
+	file, err := os.Create(c.downloadPath)
+	if err != nil {
+		return ReadResult{}, fmt.Errorf("create download file %s failed: %w", c.downloadPath, err)
+	}
+	defer file.Close()
+	writer := &timedWriter{w: file}
+	var dst io.Writer = writer
+	if verifier != nil {
+		dst = io.MultiWriter(writer, verifier)
+	}
+	result, err := readBody(body, dst, startTime)
+	if err != nil {
+		return result, err
+	}
+	if conf.MinioDownloadFsync {
+		syncStart := time.Now()
+		if err := file.Sync(); err != nil {
+			return result, fmt.Errorf("sync download file %s failed: %w", c.downloadPath, err)
+		}
+		writer.elapsed += time.Since(syncStart)
+	}
+	result.DiskWrite = writer.elapsed
+	result.Transfer -= writer.elapsed
+	return result, nil
+}
+
+// timedWriter sums the time spent in Write
+type timedWriter struct {
+	w       io.Writer
+	elapsed time.Duration
+}
+
+func (t *timedWriter) Write(p []byte) (int, error) {
+	startTime := time.Now()
+	n, err := t.w.Write(p)
+	t.elapsed += time.Since(startTime)
+	return n, err
+}
+
+// readBody copies body to dst, the first Read of a minio object sends the request so it marks the first byte
+func readBody(body io.Reader, dst io.Writer, startTime time.Time) (ReadResult, error) {
+	var result ReadResult
+	buf := make([]byte, 32*1024)
+	n, err := body.Read(buf)
+	result.FirstByte = time.Since(startTime)
+	if n > 0 {
+		written, writeErr := dst.Write(buf[:n])
+		result.Bytes = int64(written)
+		if writeErr != nil {
+			return result, writeErr
+		}
+	}
+	if err == nil {
+		var rest int64
+		rest, err = io.CopyBuffer(dst, body, buf)
+		result.Bytes += rest
+	} else if err == io.EOF {
+		err = nil
+	}
+	result.Transfer = time.Since(startTime)
+	return result, err
+}
+
+// Close removes the files the client generated or downloaded
+func (c Cli) Close() error {
+	if c.bufferType != conf.ExchangeTypeFile {
+		return nil
+	}
+	var err error
+	for _, fp := range []string{c.filename, c.downloadPath} {
+		if removeErr := os.Remove(fp); removeErr != nil && !os.IsNotExist(removeErr) {
+			err = removeErr
 		}
 	}
 	return err
 }
 
-func newCli() (*Cli, error) {
-	client, err := minio.New(conf.MinioEndpoint, &minio.Options{
-		Creds:  credentials.NewStaticV4(conf.MinioUsername, conf.MinioPassword, ""),
-		Secure: false,
-	})
+func newCli(endpoint string) (*Cli, error) {
+	sse, err := newServerSide()
+	if err != nil {
+		return nil, err
+	}
 
 	// if read from file, filename is resource
-	var filename = fmt.Sprintf("%s%s", FixedFileDir, util.RandStr(8))
-	switch conf.ExchangeType {
-	case conf.ExchangeTypeFile:
-		if err := util.DDFile(filename, conf.DataSize/1024, util.SizeUnitKB); err != nil {
-			logrus.Errorf("dd file failed: %v", err)
+	var filename = filepath.Join(conf.DataFileDir, uuid.NewString())
+	var fileSpec = newPayloadSpec(rand.Int63(), conf.DataCompressible, conf.DataFileSparse)
+	if conf.ExchangeType == conf.ExchangeTypeFile {
+		err := util.GenerateFile(filename, conf.DataSize, util.FileOptions{
+			Seed:         fileSpec.seed,
+			Compressible: conf.DataCompressible,
+			Sparse:       conf.DataFileSparse,
+			Preallocate:  conf.DataFilePreallocate,
+		})
+		if err != nil {
+			logrus.Errorf("generate file failed: %v", err)
 			return nil, err
 		}
-	default:
-		FixedBytesCache = util.RandBytes(conf.DataSize)
 	}
 
-	return &Cli{
-		client:     client,
-		dataSize:   conf.DataSize,
-		bufferType: conf.ExchangeType,
-		filename:   filename,
-	}, err
+	cli := &Cli{
+		dataSize:     conf.DataSize,
+		bufferType:   conf.ExchangeType,
+		filename:     filename,
+		downloadPath: filename + "_download",
+		sse:          sse,
+		fileSpec:     fileSpec,
+		seed:         rand.Int63(),
+	}
+	if err := cli.connect(endpoint); err != nil {
+		return nil, err
+	}
+	register(cli)
+	return cli, nil
+}
+
+// connect points the client at endpoint through a transport of its own
+func (c *Cli) connect(endpoint string) error {
+	transport, err := newTransport()
+	if err != nil {
+		return err
+	}
+	var roundTripper http.RoundTripper = transport
+	c.tracer = nil
+	if conf.MinioHttpTrace {
+		c.tracer = newHttpTracer(transport)
+		roundTripper = c.tracer
+	}
+	client, err := newMinioClient(endpoint, roundTripper)
+	if err != nil {
+		return err
+	}
+	c.client = client
+	c.httpClient = &http.Client{Transport: roundTripper}
+	return nil
+}
+
+// withEndpoint returns a client of another endpoint uploading the same payload file
+func (c *Cli) withEndpoint(endpoint string) (*Cli, error) {
+	cli := *c
+	if err := cli.connect(endpoint); err != nil {
+		return nil, err
+	}
+	return &cli, nil
+}
+
+// forWorker returns the client of one worker, it shares the connections and the payload file
+// with the other workers and downloads to the worker's own file
+func (c *Cli) forWorker(downloadPath string) *Cli {
+	cli := *c
+	cli.downloadPath = downloadPath
+	register(&cli)
+	return &cli
+}
+
+func workerDownloadPath() string {
+	return filepath.Join(conf.DataFileDir, uuid.NewString()+"_download")
+}
+
+// register keeps the client for Stop to clean up its files
+func register(cli *Cli) {
+	clisLock.Lock()
+	clis = append(clis, cli)
+	clisLock.Unlock()
 }
@@ -0,0 +1,41 @@
+// Licensed to the Apache Software Foundation (ASF) under one
+// or more contributor license agreements.  See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership.  The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package minio
+
+import (
+	"bytes"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"testing"
+	"time"
+)
+
+func TestReadBody(t *testing.T) {
+	data := make([]byte, 100_000)
+	data[0], data[99_999] = 1, 2
+	dst := &bytes.Buffer{}
+	result, err := readBody(bytes.NewReader(data), dst, time.Now())
+	assert.NoError(t, err)
+	assert.Equal(t, int64(100_000), result.Bytes)
+	assert.Equal(t, data, dst.Bytes())
+	assert.LessOrEqual(t, result.FirstByte, result.Transfer)
+
+	result, err = readBody(bytes.NewReader(nil), io.Discard, time.Now())
+	assert.NoError(t, err)
+	assert.Equal(t, int64(0), result.Bytes)
+}
@@ -0,0 +1,153 @@
+// Licensed to the Apache Software Foundation (ASF) under one
+// or more contributor license agreements.  See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership.  The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package minio
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"github.com/minio/minio-go/v7"
+	"github.com/sirupsen/logrus"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"perf-storage-go/conf"
+	"strconv"
+	"strings"
+)
+
+// manifestHeader starts the first line of the manifest, it records the number of buckets the keys
+// were spread across since bucketOf places them differently for another MINIO_BUCKET_NUM
+const manifestHeader = "# bucket_num "
+
+// reservoir keeps at most limit keys, in SAMPLE mode every listed key has the same chance to be kept
+type reservoir struct {
+	limit  int
+	sample bool
+	seen   int
+	keys   []string
+}
+
+func newReservoir(limit int, sample bool) *reservoir {
+	return &reservoir{limit: limit, sample: sample, keys: make([]string, 0, limit)}
+}
+
+// add returns false once no more keys are needed
+func (r *reservoir) add(key string) bool {
+	r.seen++
+	if len(r.keys) < r.limit {
+		r.keys = append(r.keys, key)
+		return r.sample || len(r.keys) < r.limit
+	}
+	if !r.sample {
+		return false
+	}
+	if i := rand.Intn(r.seen); i < r.limit {
+		r.keys[i] = key
+	}
+	return true
+}
+
+// discoverKeys lists up to limit keys of the buckets under MINIO_DISCOVERY_PREFIX, keys placed in
+// another bucket by a run with a different MINIO_BUCKET_NUM are left alone
+func discoverKeys(client *Cli, limit int) ([]string, error) {
+	sample := conf.MinioDiscoveryMode == conf.DiscoverySample
+	r := newReservoir(limit, sample)
+	for _, bucket := range buckets {
+		if !sample && len(r.keys) >= limit {
+			break
+		}
+		if err := listBucketKeys(client, bucket, r); err != nil {
+			return nil, err
+		}
+	}
+	logrus.Infof("discovered %d of %d listed objects", len(r.keys), r.seen)
+	return r.keys, nil
+}
+
+func listBucketKeys(client *Cli, bucket string, r *reservoir) error {
+	ctx, cancel := context.WithCancel(context.TODO())
+	defer cancel()
+	opts := minio.ListObjectsOptions{Prefix: conf.MinioDiscoveryPrefix, Recursive: true}
+	for object := range client.ListObjects(ctx, bucket, opts) {
+		if object.Err != nil {
+			logrus.Errorf("list bucket %s failed: %v", bucket, object.Err)
+			return object.Err
+		}
+		if bucketOf(object.Key) != bucket {
+			continue
+		}
+		if !r.add(object.Key) {
+			return nil
+		}
+		if r.seen%100000 == 0 {
+			logrus.Infof("listed %d objects, bucket: %s", r.seen, bucket)
+		}
+	}
+	return nil
+}
+
+// loadManifest reads the keys persisted by a previous run, one per line, and the number of buckets
+// they were spread across, zero for a manifest without header
+func loadManifest(path string) ([]string, int, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer file.Close()
+	keys := make([]string, 0)
+	var bucketNum int
+	scanner := bufio.NewScanner(file)
+	for first := true; scanner.Scan(); first = false {
+		line := strings.TrimSpace(scanner.Text())
+		if first && strings.HasPrefix(line, manifestHeader) {
+			bucketNum, err = strconv.Atoi(strings.TrimPrefix(line, manifestHeader))
+			if err != nil {
+				return nil, 0, fmt.Errorf("invalid manifest header %q: %w", line, err)
+			}
+			continue
+		}
+		if line != "" {
+			keys = append(keys, line)
+		}
+	}
+	return keys, bucketNum, scanner.Err()
+}
+
+// saveManifest replaces the manifest through a rename, so an interrupted write keeps the old one
+func saveManifest(path string, bucketNum int, keys []string) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	writer := bufio.NewWriter(tmp)
+	fmt.Fprintf(writer, "%s%d\n", manifestHeader, bucketNum)
+	for _, key := range keys {
+		writer.WriteString(key)
+		writer.WriteByte('\n')
+	}
+	if err := writer.Flush(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
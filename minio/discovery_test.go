@@ -0,0 +1,69 @@
+// Licensed to the Apache Software Foundation (ASF) under one
+// or more contributor license agreements.  See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership.  The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package minio
+
+import (
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestReservoirFirst(t *testing.T) {
+	r := newReservoir(3, false)
+	assert.True(t, r.add("a"))
+	assert.True(t, r.add("b"))
+	assert.False(t, r.add("c"))
+	assert.False(t, r.add("d"))
+	assert.Equal(t, []string{"a", "b", "c"}, r.keys)
+}
+
+func TestReservoirSample(t *testing.T) {
+	r := newReservoir(10, true)
+	for i := 0; i < 1000; i++ {
+		assert.True(t, r.add(fmt.Sprintf("key-%d", i)))
+	}
+	assert.Equal(t, 1000, r.seen)
+	assert.Len(t, r.keys, 10)
+	// the sample is not simply the first keys listed
+	assert.NotEqual(t, []string{"key-0", "key-1", "key-2", "key-3", "key-4", "key-5", "key-6", "key-7", "key-8", "key-9"}, r.keys)
+}
+
+func TestManifest(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "keys")
+	_, _, err := loadManifest(path)
+	assert.NotNil(t, err)
+
+	assert.Nil(t, saveManifest(path, 1, []string{"a", "b"}))
+	assert.Nil(t, saveManifest(path, 4, []string{"c", "d", "e"}))
+	keys, bucketNum, err := loadManifest(path)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"c", "d", "e"}, keys)
+	assert.Equal(t, 4, bucketNum)
+
+	// a manifest without header does not match any bucket num
+	assert.Nil(t, os.WriteFile(path, []byte("f\ng\n"), 0644))
+	keys, bucketNum, err = loadManifest(path)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"f", "g"}, keys)
+	assert.Equal(t, 0, bucketNum)
+	matches, err := filepath.Glob(path + ".*")
+	assert.Nil(t, err)
+	assert.Empty(t, matches)
+}
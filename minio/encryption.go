@@ -0,0 +1,50 @@
+// Licensed to the Apache Software Foundation (ASF) under one
+// or more contributor license agreements.  See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership.  The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package minio
+
+import (
+	"fmt"
+	"github.com/minio/minio-go/v7/pkg/encrypt"
+	"perf-storage-go/conf"
+)
+
+func newServerSide() (encrypt.ServerSide, error) {
+	switch conf.MinioSse {
+	case conf.SseNone:
+		return nil, nil
+	case conf.SseS3:
+		return encrypt.NewSSE(), nil
+	case conf.SseKms:
+		return encrypt.NewSSEKMS(conf.MinioSseKmsKeyId, nil)
+	case conf.SseC:
+		if len(conf.MinioSseCustomerKey) != 32 {
+			return nil, fmt.Errorf("sse-c customer key must be 32 bytes, got %d", len(conf.MinioSseCustomerKey))
+		}
+		return encrypt.NewSSEC([]byte(conf.MinioSseCustomerKey))
+	default:
+		return nil, fmt.Errorf("unknown server side encryption: %s", conf.MinioSse)
+	}
+}
+
+// readSse is the encryption to send when reading, only customer keys have to be repeated
+func readSse(sse encrypt.ServerSide) encrypt.ServerSide {
+	if sse == nil || sse.Type() != encrypt.SSEC {
+		return nil
+	}
+	return sse
+}
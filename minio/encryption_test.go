@@ -0,0 +1,58 @@
+// Licensed to the Apache Software Foundation (ASF) under one
+// or more contributor license agreements.  See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership.  The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package minio
+
+import (
+	"github.com/minio/minio-go/v7/pkg/encrypt"
+	"github.com/stretchr/testify/assert"
+	"perf-storage-go/conf"
+	"testing"
+)
+
+func TestNewServerSide(t *testing.T) {
+	sse, key := conf.MinioSse, conf.MinioSseCustomerKey
+	defer func() {
+		conf.MinioSse, conf.MinioSseCustomerKey = sse, key
+	}()
+
+	conf.MinioSse = conf.SseNone
+	s, err := newServerSide()
+	assert.Nil(t, err)
+	assert.Nil(t, s)
+	assert.Nil(t, readSse(s))
+
+	conf.MinioSse = conf.SseS3
+	s, err = newServerSide()
+	assert.Nil(t, err)
+	assert.Equal(t, encrypt.S3, s.Type())
+	assert.Nil(t, readSse(s))
+
+	conf.MinioSse = conf.SseC
+	conf.MinioSseCustomerKey = "short"
+	_, err = newServerSide()
+	assert.NotNil(t, err)
+	conf.MinioSseCustomerKey = "0123456789abcdef0123456789abcdef"
+	s, err = newServerSide()
+	assert.Nil(t, err)
+	assert.Equal(t, encrypt.SSEC, s.Type())
+	assert.Equal(t, s, readSse(s))
+
+	conf.MinioSse = "UNKNOWN"
+	_, err = newServerSide()
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,98 @@
+// Licensed to the Apache Software Foundation (ASF) under one
+// or more contributor license agreements.  See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership.  The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package minio
+
+import (
+	"math/rand"
+	"sync"
+)
+
+// keySet is the live set of keys shared by the workers, keys taken out for deletion are no longer picked
+type keySet struct {
+	mutex sync.RWMutex
+	keys  []string
+	index map[string]int
+}
+
+func newKeySet(keys []string) *keySet {
+	ks := &keySet{keys: make([]string, 0, len(keys)), index: make(map[string]int, len(keys))}
+	ks.add(keys...)
+	return ks
+}
+
+func (ks *keySet) size() int {
+	ks.mutex.RLock()
+	defer ks.mutex.RUnlock()
+	return len(ks.keys)
+}
+
+func (ks *keySet) random() (string, bool) {
+	ks.mutex.RLock()
+	defer ks.mutex.RUnlock()
+	if len(ks.keys) == 0 {
+		return "", false
+	}
+	return ks.keys[rand.Intn(len(ks.keys))], true
+}
+
+func (ks *keySet) add(keys ...string) {
+	ks.mutex.Lock()
+	defer ks.mutex.Unlock()
+	for _, key := range keys {
+		if _, ok := ks.index[key]; ok {
+			continue
+		}
+		ks.index[key] = len(ks.keys)
+		ks.keys = append(ks.keys, key)
+	}
+}
+
+func (ks *keySet) snapshot() []string {
+	ks.mutex.RLock()
+	defer ks.mutex.RUnlock()
+	return append([]string(nil), ks.keys...)
+}
+
+// take removes up to n random keys and returns them
+func (ks *keySet) take(n int) []string {
+	ks.mutex.Lock()
+	defer ks.mutex.Unlock()
+	if n > len(ks.keys) {
+		n = len(ks.keys)
+	}
+	taken := make([]string, 0, n)
+	for i := 0; i < n; i++ {
+		key := ks.keys[rand.Intn(len(ks.keys))]
+		ks.remove(key)
+		taken = append(taken, key)
+	}
+	return taken
+}
+
+// remove swaps the key with the last one so removal does not shift the slice, the lock must be held
+func (ks *keySet) remove(key string) {
+	i, ok := ks.index[key]
+	if !ok {
+		return
+	}
+	last := len(ks.keys) - 1
+	ks.keys[i] = ks.keys[last]
+	ks.index[ks.keys[i]] = i
+	ks.keys = ks.keys[:last]
+	delete(ks.index, key)
+}
@@ -0,0 +1,42 @@
+// Licensed to the Apache Software Foundation (ASF) under one
+// or more contributor license agreements.  See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership.  The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package minio
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestKeySet(t *testing.T) {
+	ks := newKeySet([]string{"a", "b", "c", "a"})
+	assert.Equal(t, 3, ks.size())
+
+	taken := ks.take(2)
+	assert.Len(t, taken, 2)
+	assert.Equal(t, 1, ks.size())
+	left, ok := ks.random()
+	assert.True(t, ok)
+	assert.NotContains(t, taken, left)
+
+	ks.add(taken...)
+	assert.Equal(t, 3, ks.size())
+
+	assert.Len(t, ks.take(5), 3)
+	_, ok = ks.random()
+	assert.False(t, ok)
+}
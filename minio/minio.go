@@ -19,59 +19,83 @@ package minio
 
 import (
 	"context"
+	"fmt"
 	"github.com/minio/minio-go/v7"
 	"github.com/sirupsen/logrus"
 	"go.uber.org/ratelimit"
 	"math/rand"
+	"os"
 	"perf-storage-go/conf"
 	"perf-storage-go/metrics"
 	"perf-storage-go/util"
+	"sync"
 	"time"
 )
 
-var FixedBytesCache []byte
+var (
+	// liveKeys are the keys the workers operate on
+	liveKeys *keySet
+	// workerCtx is canceled by Stop, workers tracks the workers that have not returned yet
+	workerCtx, stopWorkers = context.WithCancel(context.Background())
+	workers                sync.WaitGroup
+)
 
 func Start() error {
 	logrus.Info("perf storage minio start")
-	client, err := newCli()
-	if err != nil {
-		logrus.Errorf("new client failed: %v", err)
-		return err
+	pick := legacyOps
+	if conf.MinioOpWeights != "" {
+		mix, err := parseOpMix(conf.MinioOpWeights)
+		if err != nil {
+			logrus.Errorf("parse operation weights failed: %v", err)
+			return err
+		}
+		if err := checkCompose(mix, conf.DataSize, conf.MinioComposeSources); err != nil {
+			return err
+		}
+		pick = mix.picks
 	}
-	ctx, cancel := context.WithTimeout(context.TODO(), 5*time.Second)
-	defer cancel()
-	bucketExists, err := client.BucketExists(ctx, conf.MinioBucketName)
+	endpointList := endpoints()
+	if len(endpointList) == 0 {
+		return fmt.Errorf("no minio endpoint configured")
+	}
+	client, err := newCli(endpointList[0])
 	if err != nil {
-		logrus.Errorf("get bucket failed: %v", err)
+		logrus.Errorf("new client failed: %v", err)
 		return err
 	}
-	if !bucketExists {
-		logrus.Infof("bucket %s not exist, create it", conf.MinioBucketName)
-		err = client.MakeBucket(context.TODO(), conf.MinioBucketName, minio.MakeBucketOptions{})
+	// one client per endpoint, shared by the workers
+	endpointClis := []*Cli{client}
+	for _, endpoint := range endpointList[1:] {
+		endpointCli, err := client.withEndpoint(endpoint)
 		if err != nil {
-			logrus.Errorf("create bucket failed: %v", err)
+			logrus.Errorf("create minio client of %s error: %v", endpoint, err)
 			return err
 		}
+		endpointClis = append(endpointClis, endpointCli)
 	}
-	listObjects := client.ListObjects(context.TODO(), conf.MinioBucketName, minio.ListObjectsOptions{})
-	nowKeys := make([]string, 0)
-	for object := range listObjects {
-		if object.Err != nil {
-			logrus.Errorf("get object failed: %v", err)
-			return object.Err
+	for _, bucket := range buckets {
+		if err := prepareBucket(client, bucket); err != nil {
+			return err
 		}
-		nowKeys = append(nowKeys, object.Key)
+	}
+	nowKeys, err := initialKeys(client)
+	if err != nil {
+		return err
 	}
 	needDataSetSize := conf.DataSetSize - len(nowKeys)
 	if needDataSetSize > 0 {
 		keys := util.GetIdList(needDataSetSize)
-		var gpool = newGPool(conf.PresetRoutineNum)
+		for i := range keys {
+			keys[i] = conf.MinioDiscoveryPrefix + keys[i]
+		}
+		var gpool = util.NewGPool(conf.PresetRoutineNum)
 		for _, key := range keys {
 			var newKey = key
-			gpool.newTask(func() {
-				logrus.Infof("start put dataset object, bucket: %s, key: %s, size: %d", conf.MinioBucketName, newKey, conf.DataSize)
+			gpool.NewTask(func() {
+				logrus.Infof("start put dataset object, bucket: %s, key: %s, size: %d", bucketOf(newKey), newKey, conf.DataSize)
 				startTime := time.Now()
-				_, err := client.PutObject(context.TODO(), conf.MinioBucketName, newKey, conf.DataSize)
+				ctx := withOperation(context.TODO(), conf.OperationTypeInsert)
+				_, err := client.PutObject(ctx, bucketOf(newKey), newKey, conf.DataSize)
 				if err != nil {
 					metrics.FailCount.WithLabelValues(conf.StorageTypeMinio, conf.OperationTypeInsert).Inc()
 					logrus.Errorf("put dataset object key: %s , error: %v", newKey, err)
@@ -86,56 +110,48 @@ func Start() error {
 						time.Sleep(intervalTime - execTime)
 					}
 				}
-				logrus.Infof("put dataset object, bucket: %s, key: %s, success", conf.MinioBucketName, newKey)
+				logrus.Infof("put dataset object, bucket: %s, key: %s, success", bucketOf(newKey), newKey)
 			})
 		}
-		gpool.wait()
+		gpool.Wait()
 		nowKeys = append(nowKeys, keys...)
 	}
+	versions := newVersionCache(conf.MinioVersionHistory)
+	if versioned() && conf.MinioPresetVersions > 0 {
+		presetVersions(client, nowKeys, versions)
+	}
 	logrus.Info("preset data end")
+	if conf.MinioKeyManifest != "" {
+		if err := saveManifest(conf.MinioKeyManifest, len(buckets), nowKeys); err != nil {
+			logrus.Errorf("save key manifest failed: %v", err)
+		}
+	}
+	liveKeys = newKeySet(nowKeys)
 	for i := 0; i < conf.RoutineNum; i++ {
+		var index = i
+		workers.Add(1)
 		go func() {
+			defer workers.Done()
 			defer func() {
 				if err := recover(); err != nil {
 					logrus.Errorf("goroutine error: %v", err)
 				}
 			}()
 			limiter := ratelimit.New(conf.RoutineRateLimit)
-			client, err := newCli()
-			if err != nil {
-				logrus.Errorf("create minio client error: %v", err)
-				return
+			w := &worker{keys: liveKeys, cursor: &rangeCursor{}, versions: versions}
+			downloadPath := workerDownloadPath()
+			if conf.MinioEndpointMode == conf.EndpointRoundRobin {
+				for _, endpointCli := range endpointClis {
+					w.clients = append(w.clients, endpointCli.forWorker(downloadPath))
+				}
+			} else {
+				w.client = endpointClis[index%len(endpointClis)].forWorker(downloadPath)
 			}
-			for {
+			for workerCtx.Err() == nil {
 				startTime := time.Now()
 				limiter.Take()
-				randomF := rand.Float64()
-				if randomF < conf.ReadOpPercent {
-					key := nowKeys[rand.Intn(len(nowKeys))]
-					logrus.Infof("start get object, bucket: %s, key: %s", conf.MinioBucketName, key)
-					err := client.GetObject(context.TODO(), conf.MinioBucketName, key, minio.GetObjectOptions{})
-					if err != nil {
-						metrics.FailCount.WithLabelValues(conf.StorageTypeMinio, conf.OperationTypeREAD).Inc()
-						logrus.Errorf("get object, bucket: %s, key: %s, error: %v", conf.MinioBucketName, key, err)
-					} else {
-						metrics.SuccessCount.WithLabelValues(conf.StorageTypeMinio, conf.OperationTypeREAD).Inc()
-						metrics.SuccessLatency.WithLabelValues(conf.StorageTypeMinio, conf.OperationTypeREAD).Observe(float64(time.Since(startTime)))
-						logrus.Infof("get object, bucket: %s, key: %s, success", conf.MinioBucketName, key)
-					}
-				}
-				if randomF < conf.UpdateOpPercent {
-					key := nowKeys[rand.Intn(len(nowKeys))]
-					logrus.Infof("start put object, bucket: %s, key: %s", conf.MinioBucketName, key)
-					startTime := time.Now()
-					_, err := client.PutObject(context.TODO(), conf.MinioBucketName, key, conf.DataSize)
-					if err != nil {
-						metrics.FailCount.WithLabelValues(conf.StorageTypeMinio, conf.OperationTypeUpdate).Inc()
-						logrus.Errorf("put object, bucket: %s, key: %s, error: %v", conf.MinioBucketName, key, err)
-					} else {
-						metrics.SuccessCount.WithLabelValues(conf.StorageTypeMinio, conf.OperationTypeUpdate).Inc()
-						metrics.SuccessLatency.WithLabelValues(conf.StorageTypeMinio, conf.OperationTypeUpdate).Observe(float64(time.Since(startTime)))
-						logrus.Infof("put object, bucket: %s, key: %s, success", conf.MinioBucketName, key)
-					}
+				for _, name := range pick(rand.Float64()) {
+					w.run(name)
 				}
 				if conf.ReadRateInterval != 0 {
 					execTime := time.Since(startTime)
@@ -149,3 +165,115 @@ func Start() error {
 	}
 	return nil
 }
+
+// initialKeys loads the key manifest if there is one, otherwise the keys are listed, a manifest
+// written for another MINIO_BUCKET_NUM is rebuilt from the listing
+func initialKeys(client *Cli) ([]string, error) {
+	if conf.MinioKeyManifest != "" {
+		keys, bucketNum, err := loadManifest(conf.MinioKeyManifest)
+		switch {
+		case err == nil && bucketNum == len(buckets):
+			if len(keys) > conf.DataSetSize {
+				keys = keys[:conf.DataSetSize]
+			}
+			logrus.Infof("loaded %d keys from manifest %s", len(keys), conf.MinioKeyManifest)
+			return keys, nil
+		case err == nil:
+			logrus.Warnf("key manifest %s was written for %d buckets, %d configured, discover the keys again",
+				conf.MinioKeyManifest, bucketNum, len(buckets))
+		case !os.IsNotExist(err):
+			logrus.Errorf("load key manifest failed: %v", err)
+			return nil, err
+		}
+	}
+	return discoverKeys(client, conf.DataSetSize)
+}
+
+// prepareBucket creates the bucket if needed, the keys it already holds are discovered by initialKeys,
+// keys placed in another bucket by a run with a different MINIO_BUCKET_NUM are left alone
+func prepareBucket(client *Cli, bucket string) error {
+	ctx, cancel := context.WithTimeout(context.TODO(), 5*time.Second)
+	defer cancel()
+	bucketExists, err := client.BucketExists(ctx, bucket)
+	if err != nil {
+		logrus.Errorf("get bucket %s failed: %v", bucket, err)
+		return err
+	}
+	if !bucketExists {
+		logrus.Infof("bucket %s not exist, create it", bucket)
+		err = client.MakeBucket(context.TODO(), bucket, minio.MakeBucketOptions{ObjectLocking: conf.MinioObjectLock})
+		if err != nil {
+			logrus.Errorf("create bucket %s failed: %v", bucket, err)
+			return err
+		}
+	}
+	if conf.MinioVersioning {
+		if err := client.EnableVersioning(context.TODO(), bucket); err != nil {
+			logrus.Errorf("enable bucket %s versioning failed: %v", bucket, err)
+			return err
+		}
+	}
+	return nil
+}
+
+// presetVersions overwrites every key MINIO_PRESET_VERSIONS times to build up a version history
+func presetVersions(client *Cli, keys []string, versions *versionCache) {
+	var gpool = util.NewGPool(conf.PresetRoutineNum)
+	for _, key := range keys {
+		var newKey = key
+		gpool.NewTask(func() {
+			for i := 0; i < conf.MinioPresetVersions; i++ {
+				startTime := time.Now()
+				ctx := withOperation(context.TODO(), conf.OperationTypeUpdate)
+				info, err := client.PutObject(ctx, bucketOf(newKey), newKey, conf.DataSize)
+				if err != nil {
+					metrics.FailCount.WithLabelValues(conf.StorageTypeMinio, conf.OperationTypeUpdate).Inc()
+					logrus.Errorf("put dataset object version key: %s , error: %v", newKey, err)
+					continue
+				}
+				metrics.SuccessCount.WithLabelValues(conf.StorageTypeMinio, conf.OperationTypeUpdate).Inc()
+				metrics.SuccessLatency.WithLabelValues(conf.StorageTypeMinio, conf.OperationTypeUpdate).Observe(float64(time.Since(startTime).Milliseconds()))
+				versions.add(newKey, info.VersionID)
+			}
+			logrus.Infof("put dataset object versions, bucket: %s, key: %s, versions: %d", bucketOf(newKey), newKey, conf.MinioPresetVersions)
+		})
+	}
+	gpool.Wait()
+}
+
+func observePresign(opType string, result PresignResult) {
+	// signing takes microseconds, the milliseconds keep their fraction
+	metrics.MinioSignLatency.WithLabelValues(opType).Observe(float64(result.Sign.Microseconds()) / 1000)
+	observeRead(opType, result.ReadResult)
+}
+
+func observeRead(opType string, result ReadResult) {
+	if result.FirstByte > 0 {
+		metrics.MinioFirstByteLatency.WithLabelValues(opType, conf.MinioSse).Observe(float64(result.FirstByte.Milliseconds()))
+	}
+	metrics.MinioTransferLatency.WithLabelValues(opType, conf.MinioSse).Observe(float64(result.Transfer.Milliseconds()))
+	if result.DiskWrite > 0 {
+		metrics.MinioDiskWriteLatency.WithLabelValues(opType).Observe(float64(result.DiskWrite.Milliseconds()))
+	}
+	metrics.MinioBytes.WithLabelValues(opType, conf.MinioSse).Add(float64(result.Bytes))
+}
+
+// Stop cleans up the files generated by every client, once the workers returned the live keys are
+// persisted to the key manifest
+func Stop() {
+	// the workers may still be uploading the generated files
+	stopWorkers()
+	workers.Wait()
+	if conf.MinioKeyManifest != "" && liveKeys != nil {
+		if err := saveManifest(conf.MinioKeyManifest, len(buckets), liveKeys.snapshot()); err != nil {
+			logrus.Errorf("save key manifest failed: %v", err)
+		}
+	}
+	clisLock.Lock()
+	defer clisLock.Unlock()
+	for _, cli := range clis {
+		if err := cli.Close(); err != nil {
+			logrus.Errorf("close minio client failed: %v", err)
+		}
+	}
+}
@@ -0,0 +1,124 @@
+// Licensed to the Apache Software Foundation (ASF) under one
+// or more contributor license agreements.  See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership.  The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package minio
+
+import (
+	"context"
+	"github.com/minio/minio-go/v7"
+	"github.com/sirupsen/logrus"
+	"io"
+	"perf-storage-go/conf"
+	"perf-storage-go/metrics"
+	"sync"
+	"time"
+)
+
+// defaultRawPartSize matches the minimum part size minio-go uses itself
+const defaultRawPartSize = 16 * 1024 * 1024
+
+// partRange returns the offset and length of every part of an object of size bytes
+func partRange(size, partSize int64) [][2]int64 {
+	if size <= 0 {
+		return [][2]int64{{0, 0}}
+	}
+	ranges := make([][2]int64, 0, (size+partSize-1)/partSize)
+	for offset := int64(0); offset < size; offset += partSize {
+		length := partSize
+		if offset+length > size {
+			length = size - offset
+		}
+		ranges = append(ranges, [2]int64{offset, length})
+	}
+	return ranges
+}
+
+// putObjectMultipart uploads src with the low level multipart api,
+// parts are uploaded MINIO_PART_THREADS at a time and the upload is aborted if any part fails
+func (c Cli) putObjectMultipart(ctx context.Context, name string, key string, src io.ReaderAt, size int64, opts minio.PutObjectOptions) (minio.UploadInfo, error) {
+	core := minio.Core{Client: c.client}
+	partSize := conf.MinioPartSize
+	if partSize <= 0 {
+		partSize = defaultRawPartSize
+	}
+	threads := conf.MinioPartThreads
+	if threads <= 0 {
+		threads = 1
+	}
+
+	uploadID, err := core.NewMultipartUpload(ctx, name, key, opts)
+	if err != nil {
+		return minio.UploadInfo{}, err
+	}
+
+	ranges := partRange(size, partSize)
+	parts := make([]minio.CompletePart, len(ranges))
+	var wg sync.WaitGroup
+	var once sync.Once
+	var partErr error
+	limit := make(chan struct{}, threads)
+	for i, r := range ranges {
+		partNumber, offset, length := i+1, r[0], r[1]
+		wg.Add(1)
+		limit <- struct{}{}
+		go func() {
+			defer func() {
+				<-limit
+				wg.Done()
+			}()
+			partOpts := minio.PutObjectPartOptions{SSE: opts.ServerSideEncryption}
+			if conf.MinioSendChecksum || opts.SendContentMd5 {
+				// the whole object checksum headers do not apply to parts, every part is sent with its md5
+				sum, err := checksum(conf.ChecksumMd5, io.NewSectionReader(src, offset, length))
+				if err != nil {
+					once.Do(func() {
+						partErr = err
+					})
+					return
+				}
+				partOpts.Md5Base64 = sum
+			}
+			startTime := time.Now()
+			part, err := core.PutObjectPart(ctx, name, key, uploadID, partNumber,
+				io.NewSectionReader(src, offset, length), length, partOpts)
+			if err != nil {
+				metrics.MinioPartFailures.Inc()
+				once.Do(func() {
+					partErr = err
+				})
+				return
+			}
+			metrics.MinioPartLatency.Observe(float64(time.Since(startTime).Milliseconds()))
+			parts[partNumber-1] = minio.CompletePart{PartNumber: part.PartNumber, ETag: part.ETag}
+		}()
+	}
+	wg.Wait()
+
+	if partErr == nil {
+		info, err := core.CompleteMultipartUpload(ctx, name, key, uploadID, parts, opts)
+		if err == nil {
+			return info, nil
+		}
+		partErr = err
+	}
+	// the caller's context may be the reason of the failure, abort with a fresh one
+	if err := core.AbortMultipartUpload(context.Background(), name, key, uploadID); err != nil {
+		logrus.Errorf("abort multipart upload, bucket: %s, key: %s, upload id: %s, error: %v", name, key, uploadID, err)
+	}
+	metrics.MinioMultipartAborts.Inc()
+	return minio.UploadInfo{}, partErr
+}
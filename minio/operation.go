@@ -0,0 +1,497 @@
+// Licensed to the Apache Software Foundation (ASF) under one
+// or more contributor license agreements.  See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership.  The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package minio
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/google/uuid"
+	"github.com/minio/minio-go/v7"
+	"github.com/sirupsen/logrus"
+	"math/rand"
+	"perf-storage-go/conf"
+	"perf-storage-go/metrics"
+	"perf-storage-go/util"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// opFunc runs one request of the workload and returns what it worked on for the logs, the requests
+// are sent with ctx which carries the operation name for MINIO_HTTP_TRACE
+type opFunc func(ctx context.Context, w *worker) (string, error)
+
+var operations = map[string]opFunc{
+	conf.OperationTypeREAD:        readOp,
+	conf.OperationTypeRangeRead:   rangeReadOp,
+	conf.OperationTypeUpdate:      updateOp,
+	conf.OperationTypeList:        listOp,
+	conf.OperationTypeStat:        statOp,
+	conf.OperationTypePutTagging:  putTaggingOp,
+	conf.OperationTypeGetTagging:  getTaggingOp,
+	conf.OperationTypePutMetadata: putMetadataOp,
+	conf.OperationTypeInsert:      insertOp,
+	conf.OperationTypeDelete:      deleteOp,
+	conf.OperationTypeBatchDelete: batchDeleteOp,
+	conf.OperationTypeGetVersion:  getVersionOp,
+	conf.OperationTypeListVersion: listVersionsOp,
+	conf.OperationTypeDelMarker:   deleteMarkerOp,
+	conf.OperationTypeCopy:        copyOp,
+	conf.OperationTypeCompose:     composeOp,
+	conf.OperationTypePresignGet:  presignedGetOp,
+	conf.OperationTypePresignPut:  presignedPutOp,
+}
+
+var errNoKeys = errors.New("no keys left")
+
+var errNotVersioned = errors.New("bucket versioning is not enabled")
+
+// composeMinPartSize is the smallest source ComposeObject accepts for all but the last source
+const composeMinPartSize = 5 * 1024 * 1024
+
+type weightedOp struct {
+	name string
+	// cumulative weight of this and the previous operations
+	upper float64
+}
+
+// opMix picks operations proportionally to their weights
+type opMix []weightedOp
+
+// legacyOps is used when MINIO_OP_WEIGHTS is not set, every one of READ_OP_PERCENT,
+// MINIO_RANGE_READ_OP_PERCENT and UPDATE_OP_PERCENT above f runs its operation, so one tick may
+// run several operations or none, the same as the redis workload does
+func legacyOps(f float64) []string {
+	var names []string
+	if f < conf.ReadOpPercent {
+		names = append(names, conf.OperationTypeREAD)
+	}
+	if f < conf.MinioRangeReadOpPercent {
+		names = append(names, conf.OperationTypeRangeRead)
+	}
+	if f < conf.UpdateOpPercent {
+		names = append(names, conf.OperationTypeUpdate)
+	}
+	return names
+}
+
+// parseOpMix parses weights like "READ:1,UPDATE:3"
+func parseOpMix(weights string) (opMix, error) {
+	var mix opMix
+	var total float64
+	for _, item := range strings.Split(weights, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		name, value, found := strings.Cut(item, ":")
+		if !found {
+			return nil, fmt.Errorf("invalid operation weight: %s", item)
+		}
+		if _, ok := operations[name]; !ok {
+			return nil, fmt.Errorf("unknown operation type: %s", name)
+		}
+		weight, err := strconv.ParseFloat(value, 64)
+		if err != nil || weight < 0 {
+			return nil, fmt.Errorf("invalid weight of operation %s: %s", name, value)
+		}
+		if weight == 0 {
+			continue
+		}
+		total += weight
+		mix = append(mix, weightedOp{name: name, upper: total})
+	}
+	if total == 0 {
+		return nil, fmt.Errorf("no operation has a positive weight: %s", weights)
+	}
+	for i := range mix {
+		mix[i].upper /= total
+	}
+	return mix, nil
+}
+
+// pick maps f in [0, 1) to an operation
+func (m opMix) pick(f float64) string {
+	for _, op := range m {
+		if f < op.upper {
+			return op.name
+		}
+	}
+	return m[len(m)-1].name
+}
+
+func (m opMix) has(name string) bool {
+	for _, op := range m {
+		if op.name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// checkCompose rejects a COMPOSE weight when the keys of the data set are too small to be composed
+func checkCompose(m opMix, dataSize int64, sources int) error {
+	if m.has(conf.OperationTypeCompose) && sources > 1 && dataSize < composeMinPartSize {
+		return fmt.Errorf("COMPOSE of %d sources needs a DATA_SIZE of at least %d, got %d", sources, composeMinPartSize, dataSize)
+	}
+	return nil
+}
+
+// picks runs exactly one operation per tick
+func (m opMix) picks(f float64) []string {
+	return []string{m.pick(f)}
+}
+
+// worker is the state of one workload goroutine
+type worker struct {
+	client *Cli
+	// clients are the clients of every endpoint in ROUND_ROBIN mode, one request goes to each in turn
+	clients    []*Cli
+	next       int
+	keys       *keySet
+	cursor     *rangeCursor
+	versions   *versionCache
+	listBucket int
+	listToken  string
+	// composeKey is overwritten by every COMPOSE of the worker, composed objects do not match the
+	// payload of a single key and are not read back
+	composeKey string
+}
+
+func (w *worker) nextClient() {
+	if len(w.clients) == 0 {
+		return
+	}
+	w.client = w.clients[w.next%len(w.clients)]
+	w.next++
+}
+
+// randomKey returns an empty key once every key was deleted, the op has to be skipped then
+func (w *worker) randomKey() string {
+	key, _ := w.keys.random()
+	return key
+}
+
+func (w *worker) run(name string) {
+	w.nextClient()
+	startTime := time.Now()
+	// Stop cancels workerCtx, which aborts the transfers in flight
+	target, err := operations[name](withOperation(workerCtx, name), w)
+	if err != nil && workerCtx.Err() != nil && errors.Is(err, context.Canceled) {
+		logrus.Infof("%s, target: %s, canceled by stop", name, target)
+		return
+	}
+	// no request was sent, the set was drained by deletes
+	if errors.Is(err, errNoKeys) {
+		logrus.Debugf("%s, skipped: %v", name, err)
+		return
+	}
+	if err != nil {
+		metrics.FailCount.WithLabelValues(conf.StorageTypeMinio, name).Inc()
+		logrus.Errorf("%s, target: %s, error: %v", name, target, err)
+		return
+	}
+	metrics.SuccessCount.WithLabelValues(conf.StorageTypeMinio, name).Inc()
+	metrics.SuccessLatency.WithLabelValues(conf.StorageTypeMinio, name).Observe(float64(time.Since(startTime).Milliseconds()))
+	logrus.Infof("%s, target: %s, success", name, target)
+}
+
+func readOp(ctx context.Context, w *worker) (string, error) {
+	key := w.randomKey()
+	if key == "" {
+		return "", errNoKeys
+	}
+	result, err := w.client.GetObject(ctx, bucketOf(key), key, minio.GetObjectOptions{})
+	if err != nil {
+		return objectPath(key), err
+	}
+	observeRead(conf.OperationTypeREAD, result)
+	return objectPath(key), nil
+}
+
+func rangeReadOp(ctx context.Context, w *worker) (string, error) {
+	key, opts, err := w.cursor.nextOptions(w.randomKey)
+	if key == "" {
+		return "", errNoKeys
+	}
+	if err != nil {
+		return objectPath(key), err
+	}
+	target := fmt.Sprintf("%s %s", objectPath(key), opts.Header().Get("Range"))
+	result, err := w.client.GetObject(ctx, bucketOf(key), key, opts)
+	if err != nil {
+		return target, err
+	}
+	observeRead(conf.OperationTypeRangeRead, result)
+	return target, nil
+}
+
+func updateOp(ctx context.Context, w *worker) (string, error) {
+	key := w.randomKey()
+	if key == "" {
+		return "", errNoKeys
+	}
+	info, err := w.client.PutObject(ctx, bucketOf(key), key, conf.DataSize)
+	if err != nil {
+		return objectPath(key), err
+	}
+	w.versions.add(key, info.VersionID)
+	return objectPath(key), nil
+}
+
+// listOp fetches one page per call and carries on from where the previous page of this worker stopped
+// and moves on to the next bucket once the listing of a bucket ends
+func listOp(ctx context.Context, w *worker) (string, error) {
+	bucket := buckets[w.listBucket]
+	target := fmt.Sprintf("%s prefix %q after %q", bucket, conf.MinioListPrefix, w.listToken)
+	result, err := w.client.ListObjectsPage(ctx, bucket, conf.MinioListPrefix, conf.MinioListDelimiter, w.listToken, conf.MinioListPageSize)
+	if err == nil && result.IsTruncated {
+		w.listToken = result.NextContinuationToken
+	} else {
+		// the listing of this bucket ended, the next page comes from the next bucket
+		w.listToken = ""
+		w.listBucket = (w.listBucket + 1) % len(buckets)
+	}
+	if err != nil {
+		return target, err
+	}
+	metrics.MinioListedObjects.Add(float64(len(result.Contents) + len(result.CommonPrefixes)))
+	return target, nil
+}
+
+func statOp(ctx context.Context, w *worker) (string, error) {
+	key := w.randomKey()
+	if key == "" {
+		return "", errNoKeys
+	}
+	_, err := w.client.StatObject(ctx, bucketOf(key), key)
+	return objectPath(key), err
+}
+
+func putTaggingOp(ctx context.Context, w *worker) (string, error) {
+	key := w.randomKey()
+	if key == "" {
+		return "", errNoKeys
+	}
+	return objectPath(key), w.client.PutObjectTagging(ctx, bucketOf(key), key, map[string]string{
+		"perf": util.RandStr(8),
+	})
+}
+
+func getTaggingOp(ctx context.Context, w *worker) (string, error) {
+	key := w.randomKey()
+	if key == "" {
+		return "", errNoKeys
+	}
+	_, err := w.client.GetObjectTagging(ctx, bucketOf(key), key)
+	return objectPath(key), err
+}
+
+// putMetadataOp reads the user metadata and writes it back with one more entry, so the payload
+// metadata used by the verify mode survives
+func putMetadataOp(ctx context.Context, w *worker) (string, error) {
+	key := w.randomKey()
+	if key == "" {
+		return "", errNoKeys
+	}
+	info, err := w.client.StatObject(ctx, bucketOf(key), key)
+	if err != nil {
+		return objectPath(key), err
+	}
+	metadata := make(map[string]string, len(info.UserMetadata)+1)
+	for k, v := range info.UserMetadata {
+		metadata[k] = v
+	}
+	metadata["Perf-Touched"] = strconv.FormatInt(time.Now().UnixMilli(), 10)
+	return objectPath(key), w.client.ReplaceMetadata(ctx, bucketOf(key), key, metadata)
+}
+
+// insertOp writes a new key and makes it visible to the other operations once written
+func insertOp(ctx context.Context, w *worker) (string, error) {
+	key := uuid.NewString()
+	info, err := w.client.PutObject(ctx, bucketOf(key), key, conf.DataSize)
+	if err != nil {
+		return objectPath(key), err
+	}
+	w.versions.add(key, info.VersionID)
+	w.keys.add(key)
+	return objectPath(key), nil
+}
+
+// deleteOp takes the key out of the live set first so no other worker reads it while it is removed
+func deleteOp(ctx context.Context, w *worker) (string, error) {
+	keys := w.keys.take(1)
+	if len(keys) == 0 {
+		return "", errNoKeys
+	}
+	if err := w.client.RemoveObject(ctx, bucketOf(keys[0]), keys[0]); err != nil {
+		w.keys.add(keys...)
+		return objectPath(keys[0]), err
+	}
+	w.versions.drop(keys[0])
+	return objectPath(keys[0]), nil
+}
+
+func batchDeleteOp(ctx context.Context, w *worker) (string, error) {
+	keys := w.keys.take(conf.MinioDeleteBatchSize)
+	if len(keys) == 0 {
+		return "", errNoKeys
+	}
+	target := fmt.Sprintf("%d objects", len(keys))
+	errs := make(map[string]error)
+	for bucket, bucketKeys := range groupByBucket(keys) {
+		for key, err := range w.client.RemoveObjects(ctx, bucket, bucketKeys) {
+			errs[key] = err
+		}
+	}
+	for _, key := range keys {
+		if _, ok := errs[key]; !ok {
+			w.versions.drop(key)
+		}
+	}
+	metrics.MinioBatchDeleteObjects.WithLabelValues("success").Add(float64(len(keys) - len(errs)))
+	if len(errs) == 0 {
+		return target, nil
+	}
+	metrics.MinioBatchDeleteObjects.WithLabelValues("fail").Add(float64(len(errs)))
+	failed := make([]string, 0, len(errs))
+	for key, err := range errs {
+		logrus.Errorf("batch delete object, bucket: %s, key: %s, error: %v", bucketOf(key), key, err)
+		failed = append(failed, key)
+	}
+	w.keys.add(failed...)
+	return target, fmt.Errorf("%d of %d objects not deleted", len(errs), len(keys))
+}
+
+// getVersionOp reads a random earlier version of a key, the versions are listed once if none was written by this run
+func getVersionOp(ctx context.Context, w *worker) (string, error) {
+	key := w.randomKey()
+	if key == "" {
+		return "", errNoKeys
+	}
+	versionId, ok := w.versions.random(key)
+	if !ok {
+		versionIds, err := w.client.ListVersions(ctx, bucketOf(key), key)
+		if err != nil {
+			return objectPath(key), err
+		}
+		if len(versionIds) == 0 {
+			return objectPath(key), fmt.Errorf("no version of %s found", key)
+		}
+		w.versions.add(key, versionIds...)
+		versionId = versionIds[rand.Intn(len(versionIds))]
+	}
+	target := fmt.Sprintf("%s version %s", objectPath(key), versionId)
+	result, err := w.client.GetObject(ctx, bucketOf(key), key, minio.GetObjectOptions{VersionID: versionId})
+	if err != nil {
+		return target, err
+	}
+	observeRead(conf.OperationTypeGetVersion, result)
+	return target, nil
+}
+
+func listVersionsOp(ctx context.Context, w *worker) (string, error) {
+	bucket := buckets[rand.Intn(len(buckets))]
+	target := fmt.Sprintf("%s prefix %q", bucket, conf.MinioListPrefix)
+	listed, markers, err := w.client.ListVersionsPage(ctx, bucket, conf.MinioListPrefix, conf.MinioListPageSize)
+	metrics.MinioListedVersions.WithLabelValues("version").Add(float64(listed - markers))
+	metrics.MinioListedVersions.WithLabelValues("delete_marker").Add(float64(markers))
+	return target, err
+}
+
+// deleteMarkerOp hides a key behind a delete marker, its versions stay in the bucket and are
+// still counted by LIST_VERSIONS
+func deleteMarkerOp(ctx context.Context, w *worker) (string, error) {
+	if !versioned() {
+		return "", errNotVersioned
+	}
+	return deleteOp(ctx, w)
+}
+
+// copyOp copies a key to a new key, which lands in another bucket when the keys are spread across buckets
+func copyOp(ctx context.Context, w *worker) (string, error) {
+	srcKey := w.randomKey()
+	if srcKey == "" {
+		return "", errNoKeys
+	}
+	dstKey := uuid.NewString()
+	target := fmt.Sprintf("%s to %s", objectPath(srcKey), objectPath(dstKey))
+	info, err := w.client.CopyObject(ctx, bucketOf(srcKey), srcKey, bucketOf(dstKey), dstKey)
+	if err != nil {
+		return target, err
+	}
+	// a copy response carries no size, every key of the live set is DATA_SIZE bytes
+	metrics.MinioCopyBytes.WithLabelValues(conf.OperationTypeCopy, conf.MinioSse).Add(float64(conf.DataSize))
+	w.versions.add(dstKey, info.VersionID)
+	w.keys.add(dstKey)
+	return target, nil
+}
+
+func composeOp(ctx context.Context, w *worker) (string, error) {
+	if w.composeKey == "" {
+		w.composeKey = "compose-" + uuid.NewString()
+	}
+	srcs := make([]minio.CopySrcOptions, 0, conf.MinioComposeSources)
+	for i := 0; i < conf.MinioComposeSources; i++ {
+		key := w.randomKey()
+		if key == "" {
+			return "", errNoKeys
+		}
+		srcs = append(srcs, minio.CopySrcOptions{Bucket: bucketOf(key), Object: key})
+	}
+	target := fmt.Sprintf("%d objects to %s", len(srcs), objectPath(w.composeKey))
+	info, err := w.client.ComposeObject(ctx, bucketOf(w.composeKey), w.composeKey, srcs)
+	if err != nil {
+		return target, err
+	}
+	size := info.Size
+	if size == 0 {
+		// a single source is copied by minio-go without reporting its size
+		size = int64(len(srcs)) * conf.DataSize
+	}
+	metrics.MinioCopyBytes.WithLabelValues(conf.OperationTypeCompose, conf.MinioSse).Add(float64(size))
+	return target, nil
+}
+
+func presignedGetOp(ctx context.Context, w *worker) (string, error) {
+	key := w.randomKey()
+	if key == "" {
+		return "", errNoKeys
+	}
+	result, err := w.client.PresignedGetObject(ctx, bucketOf(key), key)
+	if err != nil {
+		return objectPath(key), err
+	}
+	observePresign(conf.OperationTypePresignGet, result)
+	return objectPath(key), nil
+}
+
+// presignedPutOp overwrites a key like UPDATE does
+func presignedPutOp(ctx context.Context, w *worker) (string, error) {
+	key := w.randomKey()
+	if key == "" {
+		return "", errNoKeys
+	}
+	result, err := w.client.PresignedPutObject(ctx, bucketOf(key), key, conf.DataSize)
+	if err != nil {
+		return objectPath(key), err
+	}
+	observePresign(conf.OperationTypePresignPut, result)
+	return objectPath(key), nil
+}
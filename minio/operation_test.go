@@ -0,0 +1,75 @@
+// Licensed to the Apache Software Foundation (ASF) under one
+// or more contributor license agreements.  See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership.  The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package minio
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"perf-storage-go/conf"
+	"testing"
+)
+
+func TestParseOpMix(t *testing.T) {
+	mix, err := parseOpMix("READ:1, UPDATE:3,STAT:0")
+	assert.NoError(t, err)
+	assert.Len(t, mix, 2)
+	assert.Equal(t, conf.OperationTypeREAD, mix.pick(0))
+	assert.Equal(t, conf.OperationTypeREAD, mix.pick(0.2499))
+	assert.Equal(t, conf.OperationTypeUpdate, mix.pick(0.25))
+	assert.Equal(t, conf.OperationTypeUpdate, mix.pick(0.9999))
+
+	_, err = parseOpMix("READ")
+	assert.Error(t, err)
+	_, err = parseOpMix("UNKNOWN:1")
+	assert.Error(t, err)
+	_, err = parseOpMix("READ:-1")
+	assert.Error(t, err)
+	_, err = parseOpMix("READ:0")
+	assert.Error(t, err)
+}
+
+func TestCheckCompose(t *testing.T) {
+	mix, err := parseOpMix("READ:1,COMPOSE:1")
+	assert.NoError(t, err)
+	assert.Error(t, checkCompose(mix, 10*1024, 4))
+	assert.NoError(t, checkCompose(mix, 10*1024, 1))
+	assert.NoError(t, checkCompose(mix, composeMinPartSize, 4))
+
+	mix, err = parseOpMix("READ:1")
+	assert.NoError(t, err)
+	assert.NoError(t, checkCompose(mix, 10*1024, 4))
+}
+
+func TestLegacyOps(t *testing.T) {
+	read, update, rangeRead := conf.ReadOpPercent, conf.UpdateOpPercent, conf.MinioRangeReadOpPercent
+	defer func() {
+		conf.ReadOpPercent, conf.UpdateOpPercent, conf.MinioRangeReadOpPercent = read, update, rangeRead
+	}()
+	conf.ReadOpPercent, conf.UpdateOpPercent, conf.MinioRangeReadOpPercent = 0.8, 0.3, 0
+	assert.Equal(t, []string{conf.OperationTypeREAD, conf.OperationTypeUpdate}, legacyOps(0.1))
+	assert.Equal(t, []string{conf.OperationTypeREAD}, legacyOps(0.5))
+	assert.Empty(t, legacyOps(0.9))
+}
+
+func TestOpWithoutKeys(t *testing.T) {
+	w := &worker{keys: newKeySet(nil), cursor: &rangeCursor{}}
+	for _, op := range []opFunc{readOp, rangeReadOp, updateOp, statOp, copyOp, deleteOp} {
+		_, err := op(context.Background(), w)
+		assert.ErrorIs(t, err, errNoKeys)
+	}
+}
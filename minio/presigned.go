@@ -0,0 +1,142 @@
+// Licensed to the Apache Software Foundation (ASF) under one
+// or more contributor license agreements.  See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership.  The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package minio
+
+import (
+	"context"
+	"fmt"
+	"github.com/minio/minio-go/v7"
+	"io"
+	"net/http"
+	"perf-storage-go/conf"
+	"time"
+)
+
+// PresignResult describes a transfer through a presigned url
+type PresignResult struct {
+	// Sign is the time spent generating the url, no request is sent for it
+	Sign time.Duration
+	// ReadResult times the plain http request, Transfer covers the whole request for an upload
+	ReadResult
+}
+
+// presign signs the headers along with the url, so metadata and encryption headers can be sent
+func (c Cli) presign(ctx context.Context, method string, name string, key string, header http.Header) (string, time.Duration, error) {
+	startTime := time.Now()
+	u, err := c.client.PresignHeader(ctx, method, name, key, time.Second*time.Duration(conf.MinioPresignExpiry), nil, header)
+	if err != nil {
+		return "", 0, err
+	}
+	return u.String(), time.Since(startTime), nil
+}
+
+func (c Cli) PresignedPutObject(ctx context.Context, name string, key string, dataSize int64) (PresignResult, error) {
+	src, dataSize, spec, release, err := c.payload(dataSize)
+	if err != nil {
+		return PresignResult{}, err
+	}
+	defer release()
+	header := make(http.Header)
+	if c.sse != nil {
+		c.sse.Marshal(header)
+	}
+	if conf.MinioVerify {
+		for k, v := range spec.metadata() {
+			header.Set("X-Amz-Meta-"+k, v)
+		}
+	}
+	u, sign, err := c.presign(ctx, http.MethodPut, name, key, header)
+	if err != nil {
+		return PresignResult{}, err
+	}
+	result := PresignResult{Sign: sign}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, u, io.NewSectionReader(src, 0, dataSize))
+	if err != nil {
+		return result, err
+	}
+	req.Header = header
+	req.ContentLength = dataSize
+	startTime := time.Now()
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return result, err
+	}
+	defer resp.Body.Close()
+	_, err = io.Copy(io.Discard, resp.Body)
+	result.Transfer = time.Since(startTime)
+	if resp.StatusCode != http.StatusOK {
+		return result, fmt.Errorf("presigned put object %s failed: %s", key, resp.Status)
+	}
+	if err != nil {
+		return result, fmt.Errorf("read presigned put object %s response failed: %w", key, err)
+	}
+	result.Bytes = dataSize
+	return result, nil
+}
+
+func (c Cli) PresignedGetObject(ctx context.Context, name string, key string) (PresignResult, error) {
+	header := make(http.Header)
+	if sse := readSse(c.sse); sse != nil {
+		sse.Marshal(header)
+	}
+	u, sign, err := c.presign(ctx, http.MethodGet, name, key, header)
+	if err != nil {
+		return PresignResult{}, err
+	}
+	result := PresignResult{Sign: sign}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return result, err
+	}
+	req.Header = header
+	startTime := time.Now()
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return result, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return result, fmt.Errorf("presigned get object %s failed: %s", key, resp.Status)
+	}
+	var verifier *payloadVerifier
+	var dst = io.Discard
+	if conf.MinioVerify {
+		verifier = newPayloadVerifier(key, func() (minio.ObjectInfo, error) {
+			return minio.ToObjectInfo(name, key, resp.Header)
+		})
+		dst = verifier
+	}
+	switch c.bufferType {
+	case conf.ExchangeTypeFile:
+		result.ReadResult, err = c.download(resp.Body, verifier, startTime)
+	default:
+		result.ReadResult, err = readBody(resp.Body, dst, startTime)
+	}
+	if err != nil {
+		return result, err
+	}
+	if verifier != nil {
+		if err := verifier.verify(); err != nil {
+			return result, err
+		}
+	}
+	if conf.MinioReadVerifySize && resp.ContentLength >= 0 && resp.ContentLength != result.Bytes {
+		return result, fmt.Errorf("object %s size mismatch, expect %d, read %d", key, resp.ContentLength, result.Bytes)
+	}
+	return result, nil
+}
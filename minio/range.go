@@ -0,0 +1,63 @@
+// Licensed to the Apache Software Foundation (ASF) under one
+// or more contributor license agreements.  See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership.  The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package minio
+
+import (
+	"github.com/minio/minio-go/v7"
+	"math/rand"
+	"perf-storage-go/conf"
+)
+
+// rangeCursor picks the next range to read, one cursor per worker keeps the sequential scan position
+type rangeCursor struct {
+	key    string
+	offset int64
+}
+
+// next returns the key and the first and last byte to read, a negative end means the last -end bytes
+func (r *rangeCursor) next(randomKey func() string, objectSize, rangeSize int64) (string, int64, int64) {
+	if rangeSize >= objectSize {
+		return randomKey(), 0, objectSize - 1
+	}
+	switch conf.MinioRangeOffset {
+	case conf.RangeOffsetTail:
+		return randomKey(), 0, -rangeSize
+	case conf.RangeOffsetSequential:
+		if r.key == "" || r.offset >= objectSize {
+			r.key = randomKey()
+			r.offset = 0
+		}
+		start := r.offset
+		end := start + rangeSize - 1
+		if end >= objectSize {
+			end = objectSize - 1
+		}
+		r.offset = end + 1
+		return r.key, start, end
+	default:
+		start := rand.Int63n(objectSize - rangeSize + 1)
+		return randomKey(), start, start + rangeSize - 1
+	}
+}
+
+func (r *rangeCursor) nextOptions(randomKey func() string) (string, minio.GetObjectOptions, error) {
+	key, start, end := r.next(randomKey, conf.DataSize, conf.MinioRangeSize)
+	opts := minio.GetObjectOptions{}
+	err := opts.SetRange(start, end)
+	return key, opts, err
+}
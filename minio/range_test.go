@@ -0,0 +1,57 @@
+// Licensed to the Apache Software Foundation (ASF) under one
+// or more contributor license agreements.  See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership.  The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package minio
+
+import (
+	"github.com/stretchr/testify/assert"
+	"perf-storage-go/conf"
+	"testing"
+)
+
+func TestRangeCursorNext(t *testing.T) {
+	offset := conf.MinioRangeOffset
+	defer func() {
+		conf.MinioRangeOffset = offset
+	}()
+	keys := func() string {
+		return "a"
+	}
+
+	conf.MinioRangeOffset = conf.RangeOffsetSequential
+	cursor := &rangeCursor{}
+	for _, expect := range [][2]int64{{0, 3}, {4, 7}, {8, 9}, {0, 3}} {
+		key, start, end := cursor.next(keys, 10, 4)
+		assert.Equal(t, "a", key)
+		assert.Equal(t, expect, [2]int64{start, end})
+	}
+
+	conf.MinioRangeOffset = conf.RangeOffsetTail
+	_, start, end := cursor.next(keys, 10, 4)
+	assert.Equal(t, [2]int64{0, -4}, [2]int64{start, end})
+
+	conf.MinioRangeOffset = conf.RangeOffsetRandom
+	for i := 0; i < 100; i++ {
+		_, start, end := cursor.next(keys, 10, 4)
+		assert.GreaterOrEqual(t, start, int64(0))
+		assert.Equal(t, start+3, end)
+		assert.Less(t, end, int64(10))
+	}
+
+	_, start, end = cursor.next(keys, 10, 20)
+	assert.Equal(t, [2]int64{0, 9}, [2]int64{start, end})
+}
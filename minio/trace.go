@@ -0,0 +1,171 @@
+// Licensed to the Apache Software Foundation (ASF) under one
+// or more contributor license agreements.  See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership.  The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package minio
+
+import (
+	"context"
+	"crypto/tls"
+	"github.com/prometheus/client_golang/prometheus"
+	"io"
+	"net/http"
+	"net/http/httptrace"
+	"perf-storage-go/metrics"
+	"sync"
+	"time"
+)
+
+type operationKey struct{}
+
+// withOperation labels the http phases of the requests sent with ctx
+func withOperation(ctx context.Context, name string) context.Context {
+	return context.WithValue(ctx, operationKey{}, name)
+}
+
+// operationOf returns the operation of a request, requests sent without one are labelled NONE
+func operationOf(ctx context.Context) string {
+	if name, ok := ctx.Value(operationKey{}).(string); ok {
+		return name
+	}
+	return "NONE"
+}
+
+// httpTracer breaks the latency of every request down into its http phases, labelled with the
+// operation carried by the request context
+type httpTracer struct {
+	base http.RoundTripper
+}
+
+func newHttpTracer(base http.RoundTripper) *httpTracer {
+	return &httpTracer{base: base}
+}
+
+func (t *httpTracer) RoundTrip(req *http.Request) (*http.Response, error) {
+	op := operationOf(req.Context())
+	phases := &requestPhases{op: op}
+	req = req.WithContext(httptrace.WithClientTrace(req.Context(), phases.trace()))
+	resp, err := t.base.RoundTrip(req)
+	if err != nil {
+		return resp, err
+	}
+	resp.Body = &tracedBody{ReadCloser: resp.Body, op: op, start: time.Now()}
+	return resp, nil
+}
+
+// requestPhases collects the phase timings of one request, dialing may run on other goroutines
+type requestPhases struct {
+	mutex        sync.Mutex
+	op           string
+	dnsStart     time.Time
+	connectStart time.Time
+	tlsStart     time.Time
+	gotConn      time.Time
+	wroteRequest time.Time
+}
+
+func (p *requestPhases) trace() *httptrace.ClientTrace {
+	return &httptrace.ClientTrace{
+		DNSStart: func(httptrace.DNSStartInfo) {
+			p.mutex.Lock()
+			defer p.mutex.Unlock()
+			p.dnsStart = time.Now()
+		},
+		DNSDone: func(httptrace.DNSDoneInfo) {
+			p.mutex.Lock()
+			defer p.mutex.Unlock()
+			observePhase(metrics.MinioDnsLatency, p.op, p.dnsStart)
+		},
+		ConnectStart: func(string, string) {
+			p.mutex.Lock()
+			defer p.mutex.Unlock()
+			p.connectStart = time.Now()
+		},
+		ConnectDone: func(_, _ string, err error) {
+			p.mutex.Lock()
+			defer p.mutex.Unlock()
+			if err == nil {
+				observePhase(metrics.MinioConnectLatency, p.op, p.connectStart)
+			}
+		},
+		TLSHandshakeStart: func() {
+			p.mutex.Lock()
+			defer p.mutex.Unlock()
+			p.tlsStart = time.Now()
+		},
+		TLSHandshakeDone: func(_ tls.ConnectionState, err error) {
+			p.mutex.Lock()
+			defer p.mutex.Unlock()
+			if err == nil {
+				observePhase(metrics.MinioTlsLatency, p.op, p.tlsStart)
+			}
+		},
+		// the request write starts once a connection is ready, after dns, connect and tls
+		GotConn: func(httptrace.GotConnInfo) {
+			p.mutex.Lock()
+			defer p.mutex.Unlock()
+			p.gotConn = time.Now()
+		},
+		WroteRequest: func(info httptrace.WroteRequestInfo) {
+			p.mutex.Lock()
+			defer p.mutex.Unlock()
+			p.wroteRequest = time.Now()
+			if info.Err == nil && !p.gotConn.IsZero() {
+				observePhase(metrics.MinioRequestWriteLatency, p.op, p.gotConn)
+			}
+		},
+		// the time to first byte is taken from the end of the request, so it is the server time
+		// plus one round trip
+		GotFirstResponseByte: func() {
+			p.mutex.Lock()
+			defer p.mutex.Unlock()
+			if !p.wroteRequest.IsZero() {
+				observePhase(metrics.MinioTtfbLatency, p.op, p.wroteRequest)
+			}
+		},
+	}
+}
+
+// tracedBody records the body transfer once the body is drained or closed
+type tracedBody struct {
+	io.ReadCloser
+	op    string
+	start time.Time
+	once  sync.Once
+}
+
+func (b *tracedBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	if err == io.EOF {
+		b.done()
+	}
+	return n, err
+}
+
+func (b *tracedBody) Close() error {
+	b.done()
+	return b.ReadCloser.Close()
+}
+
+func (b *tracedBody) done() {
+	b.once.Do(func() {
+		observePhase(metrics.MinioBodyLatency, b.op, b.start)
+	})
+}
+
+func observePhase(histogram *prometheus.HistogramVec, op string, start time.Time) {
+	histogram.WithLabelValues(op).Observe(float64(time.Since(start).Microseconds()) / 1000)
+}
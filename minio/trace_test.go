@@ -0,0 +1,110 @@
+// Licensed to the Apache Software Foundation (ASF) under one
+// or more contributor license agreements.  See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership.  The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package minio
+
+import (
+	"context"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"perf-storage-go/conf"
+	"perf-storage-go/metrics"
+	"strings"
+	"testing"
+)
+
+// phaseSamples counts the samples of one operation, other tests may have observed other operations
+func phaseSamples(t *testing.T, histogram *prometheus.HistogramVec, op string) uint64 {
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(histogram)
+	families, err := registry.Gather()
+	assert.Nil(t, err)
+	var count uint64
+	for _, family := range families {
+		for _, metric := range family.GetMetric() {
+			for _, label := range metric.GetLabel() {
+				if label.GetName() == "operation_type" && label.GetValue() == op {
+					count += metric.GetHistogram().GetSampleCount()
+				}
+			}
+		}
+	}
+	return count
+}
+
+func TestHttpTracer(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("hello"))
+	}))
+	defer server.Close()
+
+	phases := []*prometheus.HistogramVec{metrics.MinioConnectLatency, metrics.MinioRequestWriteLatency,
+		metrics.MinioTtfbLatency, metrics.MinioBodyLatency, metrics.MinioTlsLatency}
+	before := make([]uint64, len(phases))
+	for i, phase := range phases {
+		before[i] = phaseSamples(t, phase, "TRACE_TEST")
+	}
+
+	client := &http.Client{Transport: newHttpTracer(http.DefaultTransport)}
+	req, err := http.NewRequestWithContext(withOperation(context.Background(), "TRACE_TEST"), http.MethodGet, server.URL, nil)
+	assert.Nil(t, err)
+	resp, err := client.Do(req)
+	assert.Nil(t, err)
+	body, err := io.ReadAll(resp.Body)
+	assert.Nil(t, err)
+	assert.Nil(t, resp.Body.Close())
+	assert.Equal(t, "hello", string(body))
+
+	// no dns lookup for an ip address and no tls for plain http
+	expected := []uint64{1, 1, 1, 1, 0}
+	for i, phase := range phases {
+		assert.Equal(t, expected[i], phaseSamples(t, phase, "TRACE_TEST")-before[i])
+	}
+}
+
+func TestListObjectsPageTraced(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, ok := r.URL.Query()["location"]; ok {
+			w.Write([]byte(`<LocationConstraint xmlns="http://s3.amazonaws.com/doc/2006-03-01/"></LocationConstraint>`))
+			return
+		}
+		assert.Equal(t, "2", r.URL.Query().Get("list-type"))
+		assert.Equal(t, "token", r.URL.Query().Get("continuation-token"))
+		assert.Equal(t, "10", r.URL.Query().Get("max-keys"))
+		w.Write([]byte(`<ListBucketResult xmlns="http://s3.amazonaws.com/doc/2006-03-01/"><Name>bucket</Name>` +
+			`<IsTruncated>true</IsTruncated><NextContinuationToken>next</NextContinuationToken>` +
+			`<Contents><Key>a</Key><Size>5</Size></Contents><Contents><Key>b</Key><Size>5</Size></Contents></ListBucketResult>`))
+	}))
+	defer server.Close()
+
+	before := phaseSamples(t, metrics.MinioTtfbLatency, conf.OperationTypeList)
+	tracer := newHttpTracer(http.DefaultTransport)
+	client, err := newMinioClient(strings.TrimPrefix(server.URL, "http://"), tracer)
+	assert.Nil(t, err)
+	cli := Cli{client: client, httpClient: &http.Client{Transport: tracer}, tracer: tracer}
+
+	result, err := cli.ListObjectsPage(withOperation(context.Background(), conf.OperationTypeList), "bucket", "", "", "token", 10)
+	assert.Nil(t, err)
+	assert.True(t, result.IsTruncated)
+	assert.Equal(t, "next", result.NextContinuationToken)
+	assert.Len(t, result.Contents, 2)
+	// the list request carries the operation of the caller
+	assert.GreaterOrEqual(t, phaseSamples(t, metrics.MinioTtfbLatency, conf.OperationTypeList)-before, uint64(1))
+}
@@ -0,0 +1,129 @@
+// Licensed to the Apache Software Foundation (ASF) under one
+// or more contributor license agreements.  See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership.  The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package minio
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/credentials"
+	"net"
+	"net/http"
+	"os"
+	"perf-storage-go/conf"
+	"time"
+)
+
+// newTransport starts from minio-go's default transport and applies the configured overrides
+func newTransport() (*http.Transport, error) {
+	transport, err := minio.DefaultTransport(conf.MinioSecure)
+	if err != nil {
+		return nil, err
+	}
+	if conf.MinioMaxIdleConns > 0 {
+		transport.MaxIdleConns = conf.MinioMaxIdleConns
+	}
+	if conf.MinioMaxIdleConnsHost > 0 {
+		transport.MaxIdleConnsPerHost = conf.MinioMaxIdleConnsHost
+	}
+	if conf.MinioIdleConnTimeout > 0 {
+		transport.IdleConnTimeout = time.Second * time.Duration(conf.MinioIdleConnTimeout)
+	}
+	if conf.MinioTlsTimeout > 0 {
+		transport.TLSHandshakeTimeout = time.Second * time.Duration(conf.MinioTlsTimeout)
+	}
+	if conf.MinioHeaderTimeout > 0 {
+		transport.ResponseHeaderTimeout = time.Second * time.Duration(conf.MinioHeaderTimeout)
+	}
+	if conf.MinioDialTimeout > 0 {
+		transport.DialContext = (&net.Dialer{
+			Timeout:   time.Second * time.Duration(conf.MinioDialTimeout),
+			KeepAlive: 30 * time.Second,
+		}).DialContext
+	}
+	if conf.MinioSecure {
+		if transport.TLSClientConfig == nil {
+			transport.TLSClientConfig = &tls.Config{MinVersion: tls.VersionTLS12}
+		}
+		transport.TLSClientConfig.InsecureSkipVerify = conf.MinioInsecureSkipVerify
+		if conf.MinioCaFile != "" {
+			pem, err := os.ReadFile(conf.MinioCaFile)
+			if err != nil {
+				return nil, fmt.Errorf("read ca file %s failed: %w", conf.MinioCaFile, err)
+			}
+			rootCAs, err := x509.SystemCertPool()
+			if err != nil {
+				rootCAs = x509.NewCertPool()
+			}
+			if !rootCAs.AppendCertsFromPEM(pem) {
+				return nil, fmt.Errorf("no certificate found in ca file %s", conf.MinioCaFile)
+			}
+			transport.TLSClientConfig.RootCAs = rootCAs
+		}
+	}
+	return transport, nil
+}
+
+func newCredentials() (*credentials.Credentials, error) {
+	switch conf.MinioCredentials {
+	case conf.CredentialsStatic:
+		return credentials.NewStaticV4(conf.MinioUsername, conf.MinioPassword, conf.MinioSessionToken), nil
+	case conf.CredentialsChain:
+		return credentials.NewChainCredentials([]credentials.Provider{
+			&credentials.EnvAWS{},
+			&credentials.EnvMinio{},
+			&credentials.FileAWSCredentials{Filename: conf.MinioCredentialsFile, Profile: conf.MinioCredentialsProfile},
+			&credentials.FileMinioClient{},
+			&credentials.IAM{Client: &http.Client{Transport: http.DefaultTransport}},
+		}), nil
+	default:
+		return nil, fmt.Errorf("unknown credentials type: %s", conf.MinioCredentials)
+	}
+}
+
+func bucketLookup() (minio.BucketLookupType, error) {
+	switch conf.MinioBucketLookup {
+	case conf.BucketLookupAuto:
+		return minio.BucketLookupAuto, nil
+	case conf.BucketLookupPath:
+		return minio.BucketLookupPath, nil
+	case conf.BucketLookupDns:
+		return minio.BucketLookupDNS, nil
+	default:
+		return minio.BucketLookupAuto, fmt.Errorf("unknown bucket lookup type: %s", conf.MinioBucketLookup)
+	}
+}
+
+func newMinioClient(endpoint string, transport http.RoundTripper) (*minio.Client, error) {
+	creds, err := newCredentials()
+	if err != nil {
+		return nil, err
+	}
+	lookup, err := bucketLookup()
+	if err != nil {
+		return nil, err
+	}
+	return minio.New(endpoint, &minio.Options{
+		Creds:        creds,
+		Secure:       conf.MinioSecure,
+		Transport:    transport,
+		Region:       conf.MinioRegion,
+		BucketLookup: lookup,
+	})
+}
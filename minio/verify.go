@@ -0,0 +1,230 @@
+// Licensed to the Apache Software Foundation (ASF) under one
+// or more contributor license agreements.  See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership.  The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package minio
+
+import (
+	"bytes"
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"github.com/minio/minio-go/v7"
+	"github.com/sirupsen/logrus"
+	"hash"
+	"hash/crc32"
+	"io"
+	"net/http"
+	"perf-storage-go/conf"
+	"perf-storage-go/metrics"
+	"perf-storage-go/util"
+	"strconv"
+	"strings"
+)
+
+const (
+	metaSeed    = "Perf-Seed"
+	metaContent = "Perf-Content"
+)
+
+const (
+	contentRandom       = "RANDOM"
+	contentCompressible = "COMPRESSIBLE"
+	contentZero         = "ZERO"
+)
+
+// payloadSpec is what it takes to regenerate the content of an object written by this tool
+type payloadSpec struct {
+	seed    int64
+	content string
+}
+
+func newPayloadSpec(seed int64, compressible, sparse bool) payloadSpec {
+	switch {
+	case sparse:
+		return payloadSpec{content: contentZero}
+	case compressible:
+		return payloadSpec{seed: seed, content: contentCompressible}
+	default:
+		return payloadSpec{seed: seed, content: contentRandom}
+	}
+}
+
+func (p payloadSpec) reader(size int64) io.ReaderAt {
+	if p.content == contentZero {
+		return io.NewSectionReader(zeroReaderAt{}, 0, size)
+	}
+	return util.NewPayloadReader(p.seed, size, p.content == contentRandom)
+}
+
+func (p payloadSpec) metadata() map[string]string {
+	return map[string]string{
+		metaSeed:    strconv.FormatInt(p.seed, 10),
+		metaContent: p.content,
+	}
+}
+
+func specFromHeader(header http.Header) (payloadSpec, bool) {
+	content := header.Get("X-Amz-Meta-" + metaContent)
+	seed, err := strconv.ParseInt(header.Get("X-Amz-Meta-"+metaSeed), 10, 64)
+	if content == "" || err != nil {
+		return payloadSpec{}, false
+	}
+	return payloadSpec{seed: seed, content: content}, true
+}
+
+type zeroReaderAt struct{}
+
+func (zeroReaderAt) ReadAt(p []byte, _ int64) (int, error) {
+	for i := range p {
+		p[i] = 0
+	}
+	return len(p), nil
+}
+
+func newHash(algorithm string) (hash.Hash, error) {
+	switch algorithm {
+	case conf.ChecksumMd5:
+		return md5.New(), nil
+	case conf.ChecksumCrc32c:
+		return crc32.New(crc32.MakeTable(crc32.Castagnoli)), nil
+	case conf.ChecksumSha256:
+		return sha256.New(), nil
+	default:
+		return nil, fmt.Errorf("unknown checksum algorithm: %s", algorithm)
+	}
+}
+
+// checksum returns the base64 encoded checksum of r as sent in the x-amz-checksum headers
+func checksum(algorithm string, r io.Reader) (string, error) {
+	h, err := newHash(algorithm)
+	if err != nil {
+		return "", err
+	}
+	if _, err := io.Copy(h, r); err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(h.Sum(nil)), nil
+}
+
+// setChecksum asks the server to check the uploaded content, CRC32C and SHA256 are sent as a
+// whole object header which only single part uploads accept, so they disable multipart
+func setChecksum(opts *minio.PutObjectOptions, src io.ReaderAt, size int64) error {
+	if conf.MinioChecksum == conf.ChecksumMd5 {
+		opts.SendContentMd5 = true
+		return nil
+	}
+	sum, err := checksum(conf.MinioChecksum, io.NewSectionReader(src, 0, size))
+	if err != nil {
+		return err
+	}
+	if opts.UserMetadata == nil {
+		opts.UserMetadata = map[string]string{}
+	}
+	opts.UserMetadata["X-Amz-Checksum-"+strings.ToLower(conf.MinioChecksum)] = sum
+	opts.DisableMultipart = true
+	return nil
+}
+
+// payloadVerifier compares the bytes written to it with the content regenerated from the object
+// metadata, objects without the metadata are not verified
+type payloadVerifier struct {
+	key          string
+	stat         func() (minio.ObjectInfo, error)
+	initialized  bool
+	size         int64
+	expected     io.ReaderAt
+	received     hash.Hash
+	expectedHash hash.Hash
+	offset       int64
+	mismatch     int64
+	buf          []byte
+}
+
+func newPayloadVerifier(key string, stat func() (minio.ObjectInfo, error)) *payloadVerifier {
+	return &payloadVerifier{key: key, stat: stat, mismatch: -1}
+}
+
+func (v *payloadVerifier) init() error {
+	v.initialized = true
+	info, err := v.stat()
+	if err != nil {
+		return err
+	}
+	spec, ok := specFromHeader(info.Metadata)
+	if !ok {
+		logrus.Debugf("object %s has no payload metadata, skip verify", v.key)
+		return nil
+	}
+	if v.received, err = newHash(conf.MinioChecksum); err != nil {
+		return err
+	}
+	if v.expectedHash, err = newHash(conf.MinioChecksum); err != nil {
+		return err
+	}
+	v.size = info.Size
+	v.expected = spec.reader(info.Size)
+	return nil
+}
+
+func (v *payloadVerifier) Write(p []byte) (int, error) {
+	if !v.initialized {
+		if err := v.init(); err != nil {
+			return 0, err
+		}
+	}
+	if v.expected == nil {
+		return len(p), nil
+	}
+	if cap(v.buf) < len(p) {
+		v.buf = make([]byte, len(p))
+	}
+	expected := v.buf[:len(p)]
+	n, err := v.expected.ReadAt(expected, v.offset)
+	if err != nil && err != io.EOF {
+		return 0, err
+	}
+	expected = expected[:n]
+	v.received.Write(p)
+	v.expectedHash.Write(expected)
+	if v.mismatch < 0 {
+		for i := range p {
+			if i >= n || p[i] != expected[i] {
+				v.mismatch = v.offset + int64(i)
+				break
+			}
+		}
+	}
+	v.offset += int64(len(p))
+	return len(p), nil
+}
+
+// verify must be called once the whole body was written
+func (v *payloadVerifier) verify() error {
+	if v.expected == nil {
+		return nil
+	}
+	if v.mismatch < 0 && v.offset != v.size {
+		v.mismatch = v.offset
+	}
+	if v.mismatch < 0 && bytes.Equal(v.received.Sum(nil), v.expectedHash.Sum(nil)) {
+		return nil
+	}
+	metrics.MinioVerifyMismatches.Inc()
+	logrus.Errorf("verify object %s failed, %s mismatch, first bad byte at offset %d", v.key, conf.MinioChecksum, v.mismatch)
+	return fmt.Errorf("object %s content mismatch at offset %d", v.key, v.mismatch)
+}
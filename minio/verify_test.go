@@ -0,0 +1,75 @@
+// Licensed to the Apache Software Foundation (ASF) under one
+// or more contributor license agreements.  See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership.  The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package minio
+
+import (
+	"github.com/minio/minio-go/v7"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"net/http"
+	"testing"
+)
+
+func statOf(spec payloadSpec, size int64) func() (minio.ObjectInfo, error) {
+	header := http.Header{}
+	for k, v := range spec.metadata() {
+		header.Set("X-Amz-Meta-"+k, v)
+	}
+	return func() (minio.ObjectInfo, error) {
+		return minio.ObjectInfo{Size: size, Metadata: header}, nil
+	}
+}
+
+func TestPayloadVerifier(t *testing.T) {
+	for _, spec := range []payloadSpec{
+		newPayloadSpec(3, false, false),
+		newPayloadSpec(3, true, false),
+		newPayloadSpec(3, false, true),
+	} {
+		data, err := io.ReadAll(io.NewSectionReader(spec.reader(1000), 0, 1000))
+		assert.NoError(t, err)
+
+		verifier := newPayloadVerifier("key", statOf(spec, 1000))
+		_, err = verifier.Write(data[:300])
+		assert.NoError(t, err)
+		_, err = verifier.Write(data[300:])
+		assert.NoError(t, err)
+		assert.NoError(t, verifier.verify())
+
+		corrupted := append([]byte{}, data...)
+		corrupted[512]++
+		verifier = newPayloadVerifier("key", statOf(spec, 1000))
+		_, err = verifier.Write(corrupted)
+		assert.NoError(t, err)
+		assert.ErrorContains(t, verifier.verify(), "offset 512")
+
+		verifier = newPayloadVerifier("key", statOf(spec, 1000))
+		_, err = verifier.Write(data[:999])
+		assert.NoError(t, err)
+		assert.ErrorContains(t, verifier.verify(), "offset 999")
+	}
+}
+
+func TestPayloadVerifierWithoutMetadata(t *testing.T) {
+	verifier := newPayloadVerifier("key", func() (minio.ObjectInfo, error) {
+		return minio.ObjectInfo{Size: 3, Metadata: http.Header{}}, nil
+	})
+	_, err := verifier.Write([]byte("abc"))
+	assert.NoError(t, err)
+	assert.NoError(t, verifier.verify())
+}
@@ -0,0 +1,86 @@
+// Licensed to the Apache Software Foundation (ASF) under one
+// or more contributor license agreements.  See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership.  The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package minio
+
+import (
+	"github.com/minio/minio-go/v7"
+	"math/rand"
+	"perf-storage-go/conf"
+	"sync"
+	"time"
+)
+
+// versionCache remembers the latest version ids written per key, so reads by version do not
+// have to list the versions first
+type versionCache struct {
+	mutex    sync.RWMutex
+	limit    int
+	versions map[string][]string
+}
+
+func newVersionCache(limit int) *versionCache {
+	return &versionCache{limit: limit, versions: make(map[string][]string)}
+}
+
+// add keeps at most limit versions of a key, the oldest are forgotten first
+func (vc *versionCache) add(key string, versionIds ...string) {
+	vc.mutex.Lock()
+	defer vc.mutex.Unlock()
+	for _, versionId := range versionIds {
+		if versionId == "" {
+			continue
+		}
+		versions := append(vc.versions[key], versionId)
+		if vc.limit > 0 && len(versions) > vc.limit {
+			versions = versions[len(versions)-vc.limit:]
+		}
+		vc.versions[key] = versions
+	}
+}
+
+func (vc *versionCache) random(key string) (string, bool) {
+	vc.mutex.RLock()
+	defer vc.mutex.RUnlock()
+	versions := vc.versions[key]
+	if len(versions) == 0 {
+		return "", false
+	}
+	return versions[rand.Intn(len(versions))], true
+}
+
+func (vc *versionCache) drop(key string) {
+	vc.mutex.Lock()
+	defer vc.mutex.Unlock()
+	delete(vc.versions, key)
+}
+
+// versioned reports whether the bucket keeps versions, object lock always turns versioning on
+func versioned() bool {
+	return conf.MinioVersioning || conf.MinioObjectLock
+}
+
+// setRetention locks every written version for MINIO_LOCK_RETENTION_SECONDS, object lock
+// requests have to carry a content checksum
+func setRetention(opts *minio.PutObjectOptions) {
+	if !conf.MinioObjectLock || conf.MinioLockRetentionSec <= 0 {
+		return
+	}
+	opts.Mode = minio.RetentionMode(conf.MinioLockMode)
+	opts.RetainUntilDate = time.Now().Add(time.Duration(conf.MinioLockRetentionSec) * time.Second)
+	opts.SendContentMd5 = true
+}
@@ -0,0 +1,41 @@
+// Licensed to the Apache Software Foundation (ASF) under one
+// or more contributor license agreements.  See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership.  The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package minio
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestVersionCache(t *testing.T) {
+	vc := newVersionCache(2)
+	_, ok := vc.random("a")
+	assert.False(t, ok)
+
+	vc.add("a", "v1", "", "v2", "v3")
+	assert.Equal(t, []string{"v2", "v3"}, vc.versions["a"])
+	for i := 0; i < 10; i++ {
+		v, ok := vc.random("a")
+		assert.True(t, ok)
+		assert.Contains(t, []string{"v2", "v3"}, v)
+	}
+
+	vc.drop("a")
+	_, ok = vc.random("a")
+	assert.False(t, ok)
+}
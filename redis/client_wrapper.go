@@ -35,6 +35,7 @@ func newCli() *Cli {
 	} else {
 		cli.client = newClient()
 	}
+	cli.client.AddHook(metricsHook{})
 	return cli
 }
 
@@ -73,8 +74,12 @@ func (c *Cli) Get(ctx context.Context, key string) (string, error) {
 	return c.client.Get(ctx, key).Result()
 }
 
-func (c *Cli) Set(ctx context.Context, key, val string) error {
-	return c.client.Set(ctx, key, val, time.Second*time.Duration(conf.RedisExpirationSeconds)).Err()
+func (c *Cli) Set(ctx context.Context, key, val string, expiration time.Duration) error {
+	return c.client.Set(ctx, key, val, expiration).Err()
+}
+
+func (c *Cli) Pipelined(ctx context.Context, fn func(redis.Pipeliner) error) ([]redis.Cmder, error) {
+	return c.client.Pipelined(ctx, fn)
 }
 
 func (c *Cli) Del(ctx context.Context, keys ...string) error {
@@ -86,6 +91,44 @@ func (c *Cli) Scan(ctx context.Context, match string, limit int64) ([]string, er
 	return keys, err
 }
 
+func (c *Cli) Publish(ctx context.Context, channel, message string) error {
+	return c.client.Publish(ctx, channel, message).Err()
+}
+
+func (c *Cli) Subscribe(ctx context.Context, channels ...string) (*redis.PubSub, error) {
+	pubSub := c.client.Subscribe(ctx, channels...)
+	// wait for the subscription confirmation so no message published afterwards is missed
+	if _, err := pubSub.Receive(ctx); err != nil {
+		_ = pubSub.Close()
+		return nil, err
+	}
+	return pubSub, nil
+}
+
+func (c *Cli) ScriptLoad(ctx context.Context, script string) (string, error) {
+	return c.client.ScriptLoad(ctx, script).Result()
+}
+
+func (c *Cli) EvalSha(ctx context.Context, sha string, keys []string, args ...interface{}) (interface{}, error) {
+	return c.client.EvalSha(ctx, sha, keys, args...).Result()
+}
+
+// ReadModifyWrite replaces the value of key with fn(old value) inside WATCH/MULTI/EXEC,
+// a concurrent write to key makes it fail with redis.TxFailedErr
+func (c *Cli) ReadModifyWrite(ctx context.Context, key string, expiration time.Duration, fn func(old string) string) error {
+	return c.client.Watch(ctx, func(tx *redis.Tx) error {
+		old, err := tx.Get(ctx, key).Result()
+		if err != nil && err != redis.Nil {
+			return err
+		}
+		_, err = tx.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+			pipe.Set(ctx, key, fn(old), expiration)
+			return nil
+		})
+		return err
+	}, key)
+}
+
 func (c *Cli) getLimitKeys(ctx context.Context, limit int64) ([]string, error) {
 	return c.Scan(ctx, "*", limit)
 }
@@ -0,0 +1,109 @@
+// Licensed to the Apache Software Foundation (ASF) under one
+// or more contributor license agreements.  See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership.  The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package redis
+
+import (
+	"context"
+	"fmt"
+	"github.com/go-redis/redis/v9"
+	"github.com/sirupsen/logrus"
+	"go.uber.org/ratelimit"
+	"math/rand"
+	"perf-storage-go/conf"
+	"perf-storage-go/metrics"
+	"perf-storage-go/util"
+	"sync/atomic"
+	"time"
+)
+
+// evictionKey keys are numbered so readers can pick any written key without keeping them in memory
+func evictionKey(n int64) string {
+	return fmt.Sprintf("%s%d", conf.RedisEvictionKeyPrefix, n)
+}
+
+// startEviction keeps writing new keys past maxmemory while reading random written keys,
+// the hit ratio shows how much of the working set the server keeps under eviction
+func startEviction(client *Cli) error {
+	var written, hits, misses int64
+
+	go func() {
+		if conf.RedisHitRatioInterval <= 0 {
+			return
+		}
+		ticker := time.NewTicker(time.Second * time.Duration(conf.RedisHitRatioInterval))
+		defer ticker.Stop()
+		for range ticker.C {
+			h := atomic.SwapInt64(&hits, 0)
+			m := atomic.SwapInt64(&misses, 0)
+			if h+m == 0 {
+				continue
+			}
+			ratio := float64(h) / float64(h+m)
+			metrics.RedisHitRatio.Set(ratio)
+			logrus.Infof("written keys: %d, hit ratio: %.4f", atomic.LoadInt64(&written), ratio)
+		}
+	}()
+
+	for i := 0; i < conf.RoutineNum; i++ {
+		go func() {
+			defer func() {
+				if err := recover(); err != nil {
+					logrus.Errorf("goroutine error: %v", err)
+				}
+			}()
+			limiter := ratelimit.New(conf.RoutineRateLimit)
+
+			for {
+				limiter.Take()
+				startTime := time.Now()
+				randomF := rand.Float64()
+				if n := atomic.LoadInt64(&written); randomF < conf.ReadOpPercent && n > 0 {
+					opKey := evictionKey(rand.Int63n(n))
+					if _, err := client.Get(context.Background(), opKey); err == redis.Nil {
+						atomic.AddInt64(&misses, 1)
+						metrics.RedisCacheMisses.Inc()
+						metrics.SuccessCount.WithLabelValues(conf.StorageTypeRedis, conf.OperationTypeREAD).Inc()
+						metrics.SuccessLatency.WithLabelValues(conf.StorageTypeRedis, conf.OperationTypeREAD).Observe(float64(time.Since(startTime).Milliseconds()))
+					} else if err != nil {
+						metrics.FailCount.WithLabelValues(conf.StorageTypeRedis, conf.OperationTypeREAD).Inc()
+						logrus.Errorf("get redis key: %s , error: %v", opKey, err)
+					} else {
+						atomic.AddInt64(&hits, 1)
+						metrics.RedisCacheHits.Inc()
+						metrics.SuccessCount.WithLabelValues(conf.StorageTypeRedis, conf.OperationTypeREAD).Inc()
+						metrics.SuccessLatency.WithLabelValues(conf.StorageTypeRedis, conf.OperationTypeREAD).Observe(float64(time.Since(startTime).Milliseconds()))
+					}
+				}
+
+				if randomF < conf.UpdateOpPercent {
+					startTime := time.Now()
+					opKey := evictionKey(atomic.AddInt64(&written, 1) - 1)
+					if err := client.Set(context.Background(), opKey, util.RandStr(conf.DataSize), ttl(conf.OperationTypeInsert)); err != nil {
+						metrics.FailCount.WithLabelValues(conf.StorageTypeRedis, conf.OperationTypeInsert).Inc()
+						logrus.Errorf("set redis key: %s , error: %v", opKey, err)
+					} else {
+						metrics.SuccessCount.WithLabelValues(conf.StorageTypeRedis, conf.OperationTypeInsert).Inc()
+						metrics.SuccessLatency.WithLabelValues(conf.StorageTypeRedis, conf.OperationTypeInsert).Observe(float64(time.Since(startTime).Milliseconds()))
+					}
+				}
+			}
+		}()
+	}
+
+	return nil
+}
@@ -0,0 +1,117 @@
+// Licensed to the Apache Software Foundation (ASF) under one
+// or more contributor license agreements.  See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership.  The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package redis
+
+import (
+	"context"
+	"errors"
+	"github.com/go-redis/redis/v9"
+	"net"
+	"perf-storage-go/conf"
+	"perf-storage-go/metrics"
+	"strings"
+	"time"
+)
+
+const (
+	errorTypeNil         = "nil"
+	errorTypeTimeout     = "timeout"
+	errorTypePoolTimeout = "pool_timeout"
+	errorTypeCanceled    = "canceled"
+	errorTypeNetwork     = "network"
+	errorTypeServer      = "server"
+	errorTypeOther       = "other"
+)
+
+// metricsHook records the latency and error type of every command sent to redis
+type metricsHook struct{}
+
+var _ redis.Hook = metricsHook{}
+
+func (metricsHook) DialHook(hook redis.DialHook) redis.DialHook {
+	return hook
+}
+
+func (metricsHook) ProcessHook(hook redis.ProcessHook) redis.ProcessHook {
+	return func(ctx context.Context, cmd redis.Cmder) error {
+		startTime := time.Now()
+		err := hook(ctx, cmd)
+		observeCommand(cmd.Name(), startTime, err)
+		return err
+	}
+}
+
+func (metricsHook) ProcessPipelineHook(hook redis.ProcessPipelineHook) redis.ProcessPipelineHook {
+	return func(ctx context.Context, cmds []redis.Cmder) error {
+		startTime := time.Now()
+		err := hook(ctx, cmds)
+		observeCommand("pipeline", startTime, err)
+		return err
+	}
+}
+
+func observeCommand(name string, startTime time.Time, err error) {
+	metrics.RedisCommandLatency.WithLabelValues(name).Observe(float64(time.Since(startTime).Milliseconds()))
+	if err != nil {
+		metrics.RedisCommandErrors.WithLabelValues(name, errorType(err)).Inc()
+	}
+}
+
+func errorType(err error) string {
+	var netErr net.Error
+	switch {
+	case errors.Is(err, redis.Nil):
+		return errorTypeNil
+	case errors.Is(err, context.DeadlineExceeded):
+		return errorTypeTimeout
+	case errors.Is(err, context.Canceled):
+		return errorTypeCanceled
+	// go-redis does not export its pool timeout error
+	case strings.Contains(err.Error(), "connection pool timeout"):
+		return errorTypePoolTimeout
+	case errors.As(err, &netErr) && netErr.Timeout():
+		return errorTypeTimeout
+	case errors.As(err, &netErr):
+		return errorTypeNetwork
+	case errors.As(err, new(redis.Error)):
+		return errorTypeServer
+	default:
+		return errorTypeOther
+	}
+}
+
+// exportPoolStats periodically copies the client's connection pool stats into prometheus
+func exportPoolStats(cli *Cli) {
+	if conf.RedisPoolStatsInterval <= 0 {
+		return
+	}
+	ticker := time.NewTicker(time.Second * time.Duration(conf.RedisPoolStatsInterval))
+	defer ticker.Stop()
+	last := &redis.PoolStats{}
+	for range ticker.C {
+		stats := cli.client.PoolStats()
+		// hits, misses, timeouts and stale conns are cumulative inside go-redis
+		metrics.RedisPoolHits.Add(float64(stats.Hits - last.Hits))
+		metrics.RedisPoolMisses.Add(float64(stats.Misses - last.Misses))
+		metrics.RedisPoolTimeouts.Add(float64(stats.Timeouts - last.Timeouts))
+		metrics.RedisPoolStaleConns.Add(float64(stats.StaleConns - last.StaleConns))
+		metrics.RedisPoolTotalConns.Set(float64(stats.TotalConns))
+		metrics.RedisPoolIdleConns.Set(float64(stats.IdleConns))
+		last = stats
+	}
+}
@@ -0,0 +1,35 @@
+// Licensed to the Apache Software Foundation (ASF) under one
+// or more contributor license agreements.  See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership.  The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package redis
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/go-redis/redis/v9"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestErrorType(t *testing.T) {
+	assert.Equal(t, errorTypeNil, errorType(redis.Nil))
+	assert.Equal(t, errorTypeTimeout, errorType(fmt.Errorf("get: %w", context.DeadlineExceeded)))
+	assert.Equal(t, errorTypeCanceled, errorType(fmt.Errorf("get: %w", context.Canceled)))
+	assert.Equal(t, errorTypePoolTimeout, errorType(errors.New("redis: connection pool timeout")))
+	assert.Equal(t, errorTypeOther, errorType(errors.New("unknown")))
+}
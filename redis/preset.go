@@ -0,0 +1,158 @@
+// Licensed to the Apache Software Foundation (ASF) under one
+// or more contributor license agreements.  See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership.  The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package redis
+
+import (
+	"context"
+	"github.com/go-redis/redis/v9"
+	"github.com/sirupsen/logrus"
+	"perf-storage-go/conf"
+	"perf-storage-go/metrics"
+	"perf-storage-go/util"
+	"sync/atomic"
+	"time"
+)
+
+// presetProgress counts preset keys written and logs the progress with an estimated remaining time
+type presetProgress struct {
+	total     int64
+	done      int64
+	startTime time.Time
+}
+
+func newPresetProgress(total int) *presetProgress {
+	return &presetProgress{total: int64(total), startTime: time.Now()}
+}
+
+func (p *presetProgress) add(n int) {
+	done := atomic.AddInt64(&p.done, int64(n))
+	metrics.PresetProgress.WithLabelValues(conf.StorageTypeRedis).Set(float64(done) / float64(p.total))
+}
+
+// eta extrapolates the remaining time from the average speed so far
+func (p *presetProgress) eta() time.Duration {
+	done := atomic.LoadInt64(&p.done)
+	if done == 0 {
+		return 0
+	}
+	elapsed := time.Since(p.startTime)
+	return time.Duration(float64(elapsed) / float64(done) * float64(p.total-done))
+}
+
+// report logs the progress periodically until the returned stop func is called
+func (p *presetProgress) report() func() {
+	stopCh := make(chan struct{})
+	if conf.RedisPresetLogInterval <= 0 {
+		return func() {
+			close(stopCh)
+		}
+	}
+	go func() {
+		ticker := time.NewTicker(time.Second * time.Duration(conf.RedisPresetLogInterval))
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				logrus.Infof("preset progress: %d/%d, eta: %v", atomic.LoadInt64(&p.done), p.total, p.eta().Round(time.Second))
+			case <-stopCh:
+				return
+			}
+		}
+	}()
+	return func() {
+		close(stopCh)
+	}
+}
+
+func presetData(cli *Cli) (KeySet, error) {
+	nowKeys, err := cli.getLimitKeys(context.Background(), int64(conf.DataSetSize))
+	if err != nil {
+		logrus.Errorf("get preset data failed: %v", err)
+		logrus.Infof("generate data size: %d", conf.DataSetSize)
+	}
+
+	generateSize := conf.DataSetSize - len(nowKeys)
+
+	logrus.Infof("current key size: %d, need generate data size: %d", len(nowKeys), generateSize)
+
+	if generateSize <= 0 {
+		return nowKeys, nil
+	}
+
+	presetKeys := util.GetIdList(generateSize)
+
+	batchSize := conf.RedisPresetPipeline
+	if batchSize < 1 {
+		batchSize = 1
+	}
+	progress := newPresetProgress(generateSize)
+	stop := progress.report()
+	gpool := util.NewGPool(conf.PresetRoutineNum)
+	for start := 0; start < len(presetKeys); start += batchSize {
+		end := start + batchSize
+		if end > len(presetKeys) {
+			end = len(presetKeys)
+		}
+		batch := presetKeys[start:end]
+		gpool.NewTask(func() {
+			presetBatch(cli, batch)
+			progress.add(len(batch))
+		})
+	}
+	gpool.Wait()
+	stop()
+	logrus.Infof("preset data success! cost: %v", time.Since(progress.startTime).Round(time.Second))
+
+	return append(nowKeys, presetKeys...), nil
+}
+
+// presetBatch writes the keys one by one, or in a single pipeline when there is more than one
+func presetBatch(cli *Cli, keys []string) {
+	startTime := time.Now()
+	if len(keys) == 1 {
+		if err := cli.Set(context.Background(), keys[0], util.RandStr(conf.DataSize), ttl(conf.OperationTypeInsert)); err != nil {
+			metrics.FailCount.WithLabelValues(conf.StorageTypeRedis, conf.OperationTypeInsert).Inc()
+			logrus.Errorf("set redis key: %s , error: %v", keys[0], err)
+		} else {
+			metrics.SuccessCount.WithLabelValues(conf.StorageTypeRedis, conf.OperationTypeInsert).Inc()
+			metrics.SuccessLatency.WithLabelValues(conf.StorageTypeRedis, conf.OperationTypeInsert).Observe(float64(time.Since(startTime).Milliseconds()))
+		}
+		return
+	}
+	cmds, err := cli.Pipelined(context.Background(), func(pipe redis.Pipeliner) error {
+		for _, key := range keys {
+			pipe.Set(context.Background(), key, util.RandStr(conf.DataSize), ttl(conf.OperationTypeInsert))
+		}
+		return nil
+	})
+	if err != nil && len(cmds) == 0 {
+		metrics.FailCount.WithLabelValues(conf.StorageTypeRedis, conf.OperationTypeInsert).Add(float64(len(keys)))
+		logrus.Errorf("pipeline set %d redis keys, error: %v", len(keys), err)
+		return
+	}
+	latency := float64(time.Since(startTime).Milliseconds())
+	for i, cmd := range cmds {
+		if cmd.Err() != nil {
+			metrics.FailCount.WithLabelValues(conf.StorageTypeRedis, conf.OperationTypeInsert).Inc()
+			logrus.Errorf("set redis key: %s , error: %v", keys[i], cmd.Err())
+		} else {
+			metrics.SuccessCount.WithLabelValues(conf.StorageTypeRedis, conf.OperationTypeInsert).Inc()
+			metrics.SuccessLatency.WithLabelValues(conf.StorageTypeRedis, conf.OperationTypeInsert).Observe(latency)
+		}
+	}
+}
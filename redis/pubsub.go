@@ -0,0 +1,180 @@
+// Licensed to the Apache Software Foundation (ASF) under one
+// or more contributor license agreements.  See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership.  The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package redis
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/go-redis/redis/v9"
+	"github.com/sirupsen/logrus"
+	"go.uber.org/ratelimit"
+	"perf-storage-go/conf"
+	"perf-storage-go/metrics"
+	"perf-storage-go/util"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	receiveBackoffMin = 10 * time.Millisecond
+	receiveBackoffMax = 5 * time.Second
+)
+
+// pubSubHeader is embedded at the start of every published message
+type pubSubHeader struct {
+	sendTime  time.Time
+	publisher int
+	seq       int64
+}
+
+func encodeMessage(header pubSubHeader, size int64) string {
+	prefix := fmt.Sprintf("%d|%d|%d|", header.sendTime.UnixNano(), header.publisher, header.seq)
+	if padding := size - int64(len(prefix)); padding > 0 {
+		return prefix + util.RandStr(padding)
+	}
+	return prefix
+}
+
+func decodeMessage(payload string) (pubSubHeader, error) {
+	fields := strings.SplitN(payload, "|", 4)
+	if len(fields) < 4 {
+		return pubSubHeader{}, fmt.Errorf("invalid message header: %.32s", payload)
+	}
+	sendTime, err := strconv.ParseInt(fields[0], 10, 64)
+	if err != nil {
+		return pubSubHeader{}, fmt.Errorf("invalid send time: %w", err)
+	}
+	publisher, err := strconv.Atoi(fields[1])
+	if err != nil {
+		return pubSubHeader{}, fmt.Errorf("invalid publisher: %w", err)
+	}
+	seq, err := strconv.ParseInt(fields[2], 10, 64)
+	if err != nil {
+		return pubSubHeader{}, fmt.Errorf("invalid sequence: %w", err)
+	}
+	return pubSubHeader{sendTime: time.Unix(0, sendTime), publisher: publisher, seq: seq}, nil
+}
+
+// seqTracker counts gaps in the per publisher sequence seen by one subscriber
+type seqTracker map[int]int64
+
+func (t seqTracker) lost(header pubSubHeader) int64 {
+	last := t[header.publisher]
+	if header.seq <= last {
+		return 0
+	}
+	t[header.publisher] = header.seq
+	return header.seq - last - 1
+}
+
+func channelNames() []string {
+	channels := make([]string, conf.RedisChannelNum)
+	for i := range channels {
+		channels[i] = fmt.Sprintf("%s-%d", conf.RedisChannelPrefix, i)
+	}
+	return channels
+}
+
+func startPubSub(client *Cli) error {
+	if conf.RedisChannelNum <= 0 {
+		return fmt.Errorf("invalid channel num: %d", conf.RedisChannelNum)
+	}
+	channels := channelNames()
+	logrus.Infof("start pub sub, publishers: %d, subscribers: %d, channels: %d",
+		conf.RedisPublisherNum, conf.RedisSubscriberNum, len(channels))
+
+	// subscribe before publishing so the first sequence numbers are not reported as lost
+	for i := 0; i < conf.RedisSubscriberNum; i++ {
+		channel := channels[i%len(channels)]
+		pubSub, err := client.Subscribe(context.Background(), channel)
+		if err != nil {
+			logrus.Errorf("subscribe redis channel: %s, error: %v", channel, err)
+			return err
+		}
+		go func() {
+			defer func() {
+				if err := recover(); err != nil {
+					logrus.Errorf("goroutine error: %v", err)
+				}
+			}()
+			tracker := seqTracker{}
+			backoff := receiveBackoffMin
+			for {
+				msg, err := pubSub.ReceiveMessage(context.Background())
+				if errors.Is(err, redis.ErrClosed) {
+					logrus.Warnf("redis channel: %s closed, stop receiving", channel)
+					return
+				}
+				if err != nil {
+					metrics.FailCount.WithLabelValues(conf.StorageTypeRedis, conf.OperationTypeReceive).Inc()
+					logrus.Errorf("receive redis channel: %s, error: %v, retry in %s", channel, err, backoff)
+					// back off so a persistent error does not spin the loop
+					time.Sleep(backoff)
+					if backoff *= 2; backoff > receiveBackoffMax {
+						backoff = receiveBackoffMax
+					}
+					continue
+				}
+				backoff = receiveBackoffMin
+				header, err := decodeMessage(msg.Payload)
+				if err != nil {
+					metrics.FailCount.WithLabelValues(conf.StorageTypeRedis, conf.OperationTypeReceive).Inc()
+					logrus.Errorf("decode message from channel: %s, error: %v", msg.Channel, err)
+					continue
+				}
+				metrics.SuccessCount.WithLabelValues(conf.StorageTypeRedis, conf.OperationTypeReceive).Inc()
+				metrics.RedisMessageBytes.WithLabelValues(conf.OperationTypeReceive).Add(float64(len(msg.Payload)))
+				metrics.RedisDeliveryLatency.Observe(float64(time.Since(header.sendTime).Milliseconds()))
+				if lost := tracker.lost(header); lost > 0 {
+					metrics.RedisLostMessages.Add(float64(lost))
+				}
+			}
+		}()
+	}
+
+	for i := 0; i < conf.RedisPublisherNum; i++ {
+		publisher := i
+		go func() {
+			defer func() {
+				if err := recover(); err != nil {
+					logrus.Errorf("goroutine error: %v", err)
+				}
+			}()
+			limiter := ratelimit.New(conf.RoutineRateLimit)
+			seqs := make([]int64, len(channels))
+			for n := 0; ; n++ {
+				limiter.Take()
+				idx := n % len(channels)
+				seqs[idx]++
+				startTime := time.Now()
+				payload := encodeMessage(pubSubHeader{sendTime: startTime, publisher: publisher, seq: seqs[idx]}, conf.DataSize)
+				if err := client.Publish(context.Background(), channels[idx], payload); err != nil {
+					metrics.FailCount.WithLabelValues(conf.StorageTypeRedis, conf.OperationTypePublish).Inc()
+					logrus.Errorf("publish redis channel: %s, error: %v", channels[idx], err)
+				} else {
+					metrics.SuccessCount.WithLabelValues(conf.StorageTypeRedis, conf.OperationTypePublish).Inc()
+					metrics.SuccessLatency.WithLabelValues(conf.StorageTypeRedis, conf.OperationTypePublish).Observe(float64(time.Since(startTime).Milliseconds()))
+					metrics.RedisMessageBytes.WithLabelValues(conf.OperationTypePublish).Add(float64(len(payload)))
+				}
+			}
+		}()
+	}
+	return nil
+}
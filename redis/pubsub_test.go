@@ -0,0 +1,46 @@
+// Licensed to the Apache Software Foundation (ASF) under one
+// or more contributor license agreements.  See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership.  The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package redis
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestEncodeDecodeMessage(t *testing.T) {
+	header := pubSubHeader{sendTime: time.Unix(0, 1_600_000_000_000_000_000), publisher: 3, seq: 42}
+	payload := encodeMessage(header, 128)
+	assert.Len(t, payload, 128)
+	decoded, err := decodeMessage(payload)
+	assert.NoError(t, err)
+	assert.Equal(t, header.sendTime.UnixNano(), decoded.sendTime.UnixNano())
+	assert.Equal(t, header.publisher, decoded.publisher)
+	assert.Equal(t, header.seq, decoded.seq)
+
+	_, err = decodeMessage("garbage")
+	assert.Error(t, err)
+}
+
+func TestSeqTrackerLost(t *testing.T) {
+	tracker := seqTracker{}
+	assert.Equal(t, int64(0), tracker.lost(pubSubHeader{publisher: 0, seq: 1}))
+	assert.Equal(t, int64(2), tracker.lost(pubSubHeader{publisher: 0, seq: 4}))
+	assert.Equal(t, int64(0), tracker.lost(pubSubHeader{publisher: 1, seq: 1}))
+	assert.Equal(t, int64(0), tracker.lost(pubSubHeader{publisher: 0, seq: 3}))
+}
@@ -19,6 +19,7 @@ package redis
 
 import (
 	"context"
+	"github.com/go-redis/redis/v9"
 	"github.com/sirupsen/logrus"
 	"go.uber.org/ratelimit"
 	"math/rand"
@@ -38,7 +39,21 @@ func Start() error {
 	logrus.Info("perf storage redis start")
 
 	client := newCli()
+	go exportPoolStats(client)
+
+	switch conf.RedisMode {
+	case conf.RedisModePubSub:
+		return startPubSub(client)
+	case conf.RedisModeScript:
+		return startScript(client)
+	case conf.RedisModeEviction:
+		return startEviction(client)
+	default:
+		return startKv(client)
+	}
+}
 
+func startKv(client *Cli) error {
 	keys, err := presetData(client)
 	if err != nil {
 		logrus.Errorf("preset data failed: %v", err)
@@ -60,17 +75,23 @@ func Start() error {
 				randomF := rand.Float64()
 				opKey := keys.RandElement()
 				if randomF < conf.ReadOpPercent {
-					if _, err := client.Get(context.Background(), opKey); err != nil {
-						metrics.FailCount.WithLabelValues(conf.StorageTypeRedis, conf.OperationTypeInsert).Inc()
+					if _, err := client.Get(context.Background(), opKey); err == redis.Nil {
+						// an expired or evicted key is a cache miss, not a failure
+						metrics.RedisCacheMisses.Inc()
+						metrics.SuccessCount.WithLabelValues(conf.StorageTypeRedis, conf.OperationTypeREAD).Inc()
+						metrics.SuccessLatency.WithLabelValues(conf.StorageTypeRedis, conf.OperationTypeREAD).Observe(float64(time.Since(startTime).Milliseconds()))
+					} else if err != nil {
+						metrics.FailCount.WithLabelValues(conf.StorageTypeRedis, conf.OperationTypeREAD).Inc()
 						logrus.Errorf("get redis key: %s , error: %v", opKey, err)
 					} else {
+						metrics.RedisCacheHits.Inc()
 						metrics.SuccessCount.WithLabelValues(conf.StorageTypeRedis, conf.OperationTypeREAD).Inc()
 						metrics.SuccessLatency.WithLabelValues(conf.StorageTypeRedis, conf.OperationTypeREAD).Observe(float64(time.Since(startTime).Milliseconds()))
 					}
 				}
 
 				if randomF < conf.UpdateOpPercent {
-					if err := client.Set(context.Background(), opKey, util.RandStr(conf.DataSize)); err != nil {
+					if err := client.Set(context.Background(), opKey, util.RandStr(conf.DataSize), ttl(conf.OperationTypeUpdate)); err != nil {
 						metrics.FailCount.WithLabelValues(conf.StorageTypeRedis, conf.OperationTypeUpdate).Inc()
 						logrus.Errorf("set redis key: %s , error: %v", opKey, err)
 					} else {
@@ -84,35 +105,3 @@ func Start() error {
 
 	return nil
 }
-
-func presetData(cli *Cli) (KeySet, error) {
-	nowKeys, err := cli.getLimitKeys(context.Background(), int64(conf.DataSetSize))
-	if err != nil {
-		logrus.Errorf("get preset data failed: %v", err)
-		logrus.Infof("generate data size: %d", conf.DataSetSize)
-	}
-
-	generateSize := conf.DataSetSize - len(nowKeys)
-
-	logrus.Infof("current key size: %d, need generate data size: %d", len(nowKeys), generateSize)
-
-	if generateSize <= 0 {
-		return nowKeys, nil
-	}
-
-	presetKeys := util.GetIdList(generateSize)
-
-	for _, presetKey := range presetKeys {
-		startTime := time.Now()
-		if err := cli.Set(context.Background(), presetKey, util.RandStr(conf.DataSize)); err != nil {
-			metrics.FailCount.WithLabelValues(conf.StorageTypeRedis, conf.OperationTypeInsert).Inc()
-			logrus.Errorf("set redis key: %s , error: %v", presetKey, err)
-		} else {
-			metrics.SuccessCount.WithLabelValues(conf.StorageTypeRedis, conf.OperationTypeInsert).Inc()
-			metrics.SuccessLatency.WithLabelValues(conf.StorageTypeRedis, conf.OperationTypeInsert).Observe(float64(time.Since(startTime).Milliseconds()))
-		}
-	}
-	logrus.Infof("preset data success!")
-
-	return append(nowKeys, presetKeys...), nil
-}
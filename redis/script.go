@@ -0,0 +1,123 @@
+// Licensed to the Apache Software Foundation (ASF) under one
+// or more contributor license agreements.  See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership.  The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package redis
+
+import (
+	"context"
+	"errors"
+	"github.com/go-redis/redis/v9"
+	"github.com/sirupsen/logrus"
+	"go.uber.org/ratelimit"
+	"math/rand"
+	"os"
+	"perf-storage-go/conf"
+	"perf-storage-go/metrics"
+	"perf-storage-go/util"
+	"time"
+)
+
+func loadScript() (string, error) {
+	if conf.RedisScriptFile == "" {
+		return conf.RedisScript, nil
+	}
+	script, err := os.ReadFile(conf.RedisScriptFile)
+	if err != nil {
+		return "", err
+	}
+	return string(script), nil
+}
+
+func startScript(client *Cli) error {
+	script, err := loadScript()
+	if err != nil {
+		logrus.Errorf("read script file %s failed: %v", conf.RedisScriptFile, err)
+		return err
+	}
+	sha, err := client.ScriptLoad(context.Background(), script)
+	if err != nil {
+		logrus.Errorf("load script failed: %v", err)
+		return err
+	}
+	logrus.Infof("script loaded, sha: %s", sha)
+
+	keys, err := presetData(client)
+	if err != nil {
+		logrus.Errorf("preset data failed: %v", err)
+		return err
+	}
+
+	for i := 0; i < conf.RoutineNum; i++ {
+		go func() {
+			defer func() {
+				if err := recover(); err != nil {
+					logrus.Errorf("goroutine error: %v", err)
+				}
+			}()
+			limiter := ratelimit.New(conf.RoutineRateLimit)
+
+			for {
+				limiter.Take()
+				startTime := time.Now()
+				opKey := keys.RandElement()
+				if rand.Float64() < conf.RedisTxOpPercent {
+					if err := readModifyWrite(client, opKey); err != nil {
+						metrics.FailCount.WithLabelValues(conf.StorageTypeRedis, conf.OperationTypeTx).Inc()
+						logrus.Errorf("transaction redis key: %s , error: %v", opKey, err)
+					} else {
+						metrics.SuccessCount.WithLabelValues(conf.StorageTypeRedis, conf.OperationTypeTx).Inc()
+						metrics.SuccessLatency.WithLabelValues(conf.StorageTypeRedis, conf.OperationTypeTx).Observe(float64(time.Since(startTime).Milliseconds()))
+					}
+					continue
+				}
+				_, err := client.EvalSha(context.Background(), sha, []string{opKey}, util.RandStr(conf.DataSize))
+				if redis.HasErrorPrefix(err, "NOSCRIPT") {
+					// the script cache was flushed or the node failed over, load it again
+					logrus.Warnf("script %s not found, reload it", sha)
+					if _, err = client.ScriptLoad(context.Background(), script); err == nil {
+						_, err = client.EvalSha(context.Background(), sha, []string{opKey}, util.RandStr(conf.DataSize))
+					}
+				}
+				if err != nil && err != redis.Nil {
+					metrics.FailCount.WithLabelValues(conf.StorageTypeRedis, conf.OperationTypeEvalSha).Inc()
+					logrus.Errorf("evalsha redis key: %s , error: %v", opKey, err)
+				} else {
+					metrics.SuccessCount.WithLabelValues(conf.StorageTypeRedis, conf.OperationTypeEvalSha).Inc()
+					metrics.SuccessLatency.WithLabelValues(conf.StorageTypeRedis, conf.OperationTypeEvalSha).Observe(float64(time.Since(startTime).Milliseconds()))
+				}
+			}
+		}()
+	}
+
+	return nil
+}
+
+// readModifyWrite retries the optimistic transaction on conflict up to RedisTxMaxRetries times
+func readModifyWrite(client *Cli, key string) error {
+	var err error
+	for attempt := 0; attempt <= conf.RedisTxMaxRetries; attempt++ {
+		err = client.ReadModifyWrite(context.Background(), key, ttl(conf.OperationTypeTx), func(old string) string {
+			return util.RandStr(conf.DataSize)
+		})
+		if !errors.Is(err, redis.TxFailedErr) {
+			return err
+		}
+		metrics.RedisTxConflicts.Inc()
+	}
+	metrics.RedisTxAborts.Inc()
+	return err
+}
@@ -0,0 +1,48 @@
+// Licensed to the Apache Software Foundation (ASF) under one
+// or more contributor license agreements.  See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership.  The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package redis
+
+import (
+	"perf-storage-go/conf"
+	"perf-storage-go/util"
+	"time"
+)
+
+// ttl returns the expiration of a write of opType according to REDIS_TTL_MODE, 0 means no expiration
+func ttl(opType string) time.Duration {
+	switch conf.RedisTtlMode {
+	case conf.RedisTtlModeNone:
+		return 0
+	case conf.RedisTtlModeRandom:
+		if conf.RedisTtlMaxSeconds <= conf.RedisTtlMinSeconds {
+			return seconds(int64(conf.RedisTtlMinSeconds))
+		}
+		return seconds(util.RandNumber(int64(conf.RedisTtlMinSeconds), int64(conf.RedisTtlMaxSeconds)+1))
+	case conf.RedisTtlModePerOp:
+		if opType == conf.OperationTypeInsert {
+			return seconds(int64(conf.RedisInsertTtlSeconds))
+		}
+		return seconds(int64(conf.RedisUpdateTtlSeconds))
+	default:
+		return seconds(int64(conf.RedisExpirationSeconds))
+	}
+}
+
+func seconds(n int64) time.Duration {
+	return time.Second * time.Duration(n)
+}
@@ -0,0 +1,49 @@
+// Licensed to the Apache Software Foundation (ASF) under one
+// or more contributor license agreements.  See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership.  The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package redis
+
+import (
+	"github.com/stretchr/testify/assert"
+	"perf-storage-go/conf"
+	"testing"
+	"time"
+)
+
+func TestTtl(t *testing.T) {
+	mode := conf.RedisTtlMode
+	defer func() {
+		conf.RedisTtlMode = mode
+	}()
+
+	conf.RedisTtlMode = conf.RedisTtlModeNone
+	assert.Equal(t, time.Duration(0), ttl(conf.OperationTypeUpdate))
+
+	conf.RedisTtlMode = conf.RedisTtlModeFixed
+	assert.Equal(t, seconds(int64(conf.RedisExpirationSeconds)), ttl(conf.OperationTypeUpdate))
+
+	conf.RedisTtlMode = conf.RedisTtlModeRandom
+	for i := 0; i < 100; i++ {
+		d := ttl(conf.OperationTypeUpdate)
+		assert.GreaterOrEqual(t, d, seconds(int64(conf.RedisTtlMinSeconds)))
+		assert.LessOrEqual(t, d, seconds(int64(conf.RedisTtlMaxSeconds)))
+	}
+
+	conf.RedisTtlMode = conf.RedisTtlModePerOp
+	assert.Equal(t, seconds(int64(conf.RedisInsertTtlSeconds)), ttl(conf.OperationTypeInsert))
+	assert.Equal(t, seconds(int64(conf.RedisUpdateTtlSeconds)), ttl(conf.OperationTypeUpdate))
+}
@@ -19,27 +19,50 @@ package util
 
 import (
 	"fmt"
-	"os/exec"
+	"io"
+	"os"
 )
 
-// SizeUnit dd block size unit
-type SizeUnit string
-
-const (
-	SizeUnitKB SizeUnit = "K"
-	SizeUnitMB SizeUnit = "M"
-	SizeUnitGB SizeUnit = "G"
-)
+// FileOptions control how GenerateFile fills a file
+type FileOptions struct {
+	// Seed of the content, the same seed produces the same file
+	Seed int64
+	// Compressible writes a repeating letter pattern instead of pseudo-random bytes
+	Compressible bool
+	// Sparse only sets the file size, nothing is written so the file reads as zeros
+	Sparse bool
+	// Preallocate reserves the disk blocks before writing where the platform supports it
+	Preallocate bool
+}
 
-// DDFile call dd command to generate empty file
-func DDFile(fp string, size int64, unit SizeUnit) error {
-	dataSource := "if=/dev/zero"
-	fp = fmt.Sprintf("of=%s", fp)
-	blockSize := fmt.Sprintf("bs=1%s", unit)
-	count := fmt.Sprintf("count=%d", size)
-	cmd := exec.Command("dd", dataSource, fp, blockSize, count)
-	if err := cmd.Run(); err != nil {
-		return err
+// GenerateFile creates or truncates fp and fills it with exactly size bytes
+func GenerateFile(fp string, size int64, opts FileOptions) (err error) {
+	if size < 0 {
+		return fmt.Errorf("generate file %s: negative size %d", fp, size)
+	}
+	file, err := os.OpenFile(fp, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("generate file %s: %w", fp, err)
+	}
+	defer func() {
+		if closeErr := file.Close(); closeErr != nil && err == nil {
+			err = fmt.Errorf("generate file %s: close: %w", fp, closeErr)
+		}
+	}()
+	if opts.Sparse {
+		if err := file.Truncate(size); err != nil {
+			return fmt.Errorf("generate file %s: truncate to %d bytes: %w", fp, size, err)
+		}
+		return nil
+	}
+	if opts.Preallocate && size > 0 {
+		if err := preallocate(file, size); err != nil {
+			return fmt.Errorf("generate file %s: preallocate %d bytes: %w", fp, size, err)
+		}
+	}
+	written, err := io.CopyBuffer(file, NewPayloadReader(opts.Seed, size, !opts.Compressible), make([]byte, 1024*1024))
+	if err != nil {
+		return fmt.Errorf("generate file %s: write at offset %d: %w", fp, written, err)
 	}
 	return nil
 }
@@ -19,10 +19,28 @@ package util
 
 import (
 	"github.com/stretchr/testify/assert"
+	"os"
+	"path/filepath"
 	"testing"
 )
 
-func TestDDFile(t *testing.T) {
-	err := DDFile("./test", 10, SizeUnitMB)
-	assert.NoError(t, err)
+func TestGenerateFile(t *testing.T) {
+	dir := t.TempDir()
+	for name, opts := range map[string]FileOptions{
+		"random":       {Seed: 1},
+		"compressible": {Seed: 1, Compressible: true},
+		"sparse":       {Sparse: true},
+		"preallocate":  {Seed: 1, Preallocate: true},
+	} {
+		fp := filepath.Join(dir, name)
+		assert.NoError(t, GenerateFile(fp, 1023, opts))
+		info, err := os.Stat(fp)
+		assert.NoError(t, err)
+		assert.Equal(t, int64(1023), info.Size(), name)
+	}
+}
+
+func TestGenerateFileError(t *testing.T) {
+	err := GenerateFile(filepath.Join(t.TempDir(), "missing", "file"), 10, FileOptions{})
+	assert.ErrorContains(t, err, "missing")
 }
@@ -0,0 +1,109 @@
+// Licensed to the Apache Software Foundation (ASF) under one
+// or more contributor license agreements.  See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership.  The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package util
+
+import (
+	"errors"
+	"io"
+)
+
+// PayloadReader generates size bytes of synthetic content on the fly instead of holding them in memory.
+// The byte at every offset only depends on the seed, so the same seed always yields the same content
+// and the reader can seek or be read concurrently with ReadAt.
+type PayloadReader struct {
+	seed   int64
+	size   int64
+	offset int64
+	random bool
+}
+
+// NewPayloadReader returns a reader of pseudo-random bytes if random is set,
+// otherwise of a repeating letter pattern which compresses well
+func NewPayloadReader(seed, size int64, random bool) *PayloadReader {
+	return &PayloadReader{seed: seed, size: size, random: random}
+}
+
+func (r *PayloadReader) Size() int64 {
+	return r.size
+}
+
+func (r *PayloadReader) Read(p []byte) (int, error) {
+	n, err := r.ReadAt(p, r.offset)
+	r.offset += int64(n)
+	return n, err
+}
+
+func (r *PayloadReader) ReadAt(p []byte, off int64) (int, error) {
+	if off < 0 {
+		return 0, errors.New("payload reader: negative offset")
+	}
+	if off >= r.size {
+		return 0, io.EOF
+	}
+	var err error
+	if remain := r.size - off; int64(len(p)) > remain {
+		p = p[:remain]
+		err = io.EOF
+	}
+	r.fill(p, off)
+	return len(p), err
+}
+
+func (r *PayloadReader) Seek(offset int64, whence int) (int64, error) {
+	var abs int64
+	switch whence {
+	case io.SeekStart:
+		abs = offset
+	case io.SeekCurrent:
+		abs = r.offset + offset
+	case io.SeekEnd:
+		abs = r.size + offset
+	default:
+		return 0, errors.New("payload reader: invalid whence")
+	}
+	if abs < 0 {
+		return 0, errors.New("payload reader: negative position")
+	}
+	r.offset = abs
+	return abs, nil
+}
+
+func (r *PayloadReader) fill(p []byte, off int64) {
+	if !r.random {
+		for i := range p {
+			p[i] = letters[uint64(r.seed+off+int64(i))%uint64(len(letters))]
+		}
+		return
+	}
+	// every 8 byte block is one splitmix64 output of the seed and block index
+	for i := 0; i < len(p); {
+		pos := off + int64(i)
+		v := splitMix64(uint64(r.seed) + uint64(pos>>3)*0x9E3779B97F4A7C15)
+		for shift := pos & 7; shift < 8 && i < len(p); shift++ {
+			p[i] = byte(v >> (shift * 8))
+			i++
+		}
+	}
+}
+
+func splitMix64(x uint64) uint64 {
+	x += 0x9E3779B97F4A7C15
+	x = (x ^ (x >> 30)) * 0xBF58476D1CE4E5B9
+	x = (x ^ (x >> 27)) * 0x94D049BB133111EB
+	return x ^ (x >> 31)
+}
@@ -0,0 +1,58 @@
+// Licensed to the Apache Software Foundation (ASF) under one
+// or more contributor license agreements.  See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership.  The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package util
+
+import (
+	"bytes"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"testing"
+)
+
+func TestPayloadReader(t *testing.T) {
+	for _, random := range []bool{true, false} {
+		data, err := io.ReadAll(NewPayloadReader(7, 10_001, random))
+		assert.NoError(t, err)
+		assert.Len(t, data, 10_001)
+
+		again, err := io.ReadAll(NewPayloadReader(7, 10_001, random))
+		assert.NoError(t, err)
+		assert.Equal(t, data, again)
+
+		other, err := io.ReadAll(NewPayloadReader(8, 10_001, random))
+		assert.NoError(t, err)
+		assert.NotEqual(t, data, other)
+
+		reader := NewPayloadReader(7, 10_001, random)
+		buf := make([]byte, 100)
+		n, err := reader.ReadAt(buf, 4_003)
+		assert.NoError(t, err)
+		assert.Equal(t, data[4_003:4_103], buf[:n])
+
+		n, err = reader.ReadAt(buf, 9_951)
+		assert.Equal(t, io.EOF, err)
+		assert.Equal(t, data[9_951:], buf[:n])
+
+		pos, err := reader.Seek(-1, io.SeekEnd)
+		assert.NoError(t, err)
+		assert.Equal(t, int64(10_000), pos)
+		rest, err := io.ReadAll(reader)
+		assert.NoError(t, err)
+		assert.True(t, bytes.Equal(data[10_000:], rest))
+	}
+}
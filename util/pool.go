@@ -15,28 +15,30 @@
 // specific language governing permissions and limitations
 // under the License.
 
-package minio
+package util
 
 import (
 	"github.com/sirupsen/logrus"
 	"sync"
 )
 
-// gPool simple goroutine pool
-type gPool struct {
+// GPool simple goroutine pool
+type GPool struct {
 	work     chan func()
 	capacity chan struct{}
 	wg       sync.WaitGroup
 }
 
-func newGPool(size int) *gPool {
-	return &gPool{
+// NewGPool creates a pool running at most size tasks at the same time
+func NewGPool(size int) *GPool {
+	return &GPool{
 		work:     make(chan func()),
 		capacity: make(chan struct{}, size),
 	}
 }
 
-func (p *gPool) newTask(task func()) {
+// NewTask hands the task to an idle worker, or starts a new one while the pool is not full
+func (p *GPool) NewTask(task func()) {
 	p.wg.Add(1)
 	select {
 	case p.work <- task:
@@ -45,7 +47,7 @@ func (p *gPool) newTask(task func()) {
 	}
 }
 
-func (p *gPool) worker(task func()) {
+func (p *GPool) worker(task func()) {
 	defer func() {
 		if err := recover(); err != nil {
 			logrus.Errorf("exec task failed: %v", err)
@@ -59,6 +61,7 @@ func (p *gPool) worker(task func()) {
 	}
 }
 
-func (p *gPool) wait() {
+// Wait blocks until every task handed to the pool is done
+func (p *GPool) Wait() {
 	p.wg.Wait()
 }
@@ -15,7 +15,7 @@
 // specific language governing permissions and limitations
 // under the License.
 
-package minio
+package util
 
 import (
 	"fmt"
@@ -23,42 +23,42 @@ import (
 	"time"
 )
 
-func Test_gPool_newTask(t *testing.T) {
-	pool := newGPool(3)
+func Test_GPool_NewTask(t *testing.T) {
+	pool := NewGPool(3)
 	for i := 0; i < 5; i++ {
-		pool.newTask(func() {
+		pool.NewTask(func() {
 			fmt.Println(time.Now())
 			time.Sleep(10 * time.Second)
 		})
 	}
-	pool.wait()
+	pool.Wait()
 }
 
-func Test_gPool_newTask_noClosure(t *testing.T) {
-	pool := newGPool(3)
+func Test_GPool_NewTask_noClosure(t *testing.T) {
+	pool := NewGPool(3)
 	var noClosure = func(name string) {
 		fmt.Println(name)
 	}
 	for i := 0; i < 5; i++ {
-		pool.newTask(func() {
+		pool.NewTask(func() {
 			// i take a mistake, because value copy
 			noClosure(fmt.Sprintf("%d doing...", i))
 		})
 	}
-	pool.wait()
+	pool.Wait()
 }
 
-func Test_gPool_newTask_noClosure_new(t *testing.T) {
-	pool := newGPool(3)
+func Test_GPool_NewTask_noClosure_new(t *testing.T) {
+	pool := NewGPool(3)
 	var noClosure = func(name string) {
 		fmt.Println(name)
 	}
 	for i := 0; i < 5; i++ {
 		var newName = fmt.Sprintf("%d doing...", i)
-		pool.newTask(func() {
+		pool.NewTask(func() {
 			// good job
 			noClosure(newName)
 		})
 	}
-	pool.wait()
+	pool.Wait()
 }
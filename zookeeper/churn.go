@@ -0,0 +1,225 @@
+// Licensed to the Apache Software Foundation (ASF) under one
+// or more contributor license agreements.  See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership.  The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package zookeeper
+
+import (
+	"fmt"
+	"github.com/google/uuid"
+	"github.com/protocol-laboratory/zookeeper-codec-go/codec"
+	"github.com/sirupsen/logrus"
+	"go.uber.org/ratelimit"
+	"perf-storage-go/conf"
+	"perf-storage-go/metrics"
+	"perf-storage-go/util"
+	"strconv"
+	"time"
+)
+
+func createFlags(nodeType string) (int, error) {
+	switch nodeType {
+	case conf.ZkNodePersistent:
+		return flagPersistent, nil
+	case conf.ZkNodeEphemeral:
+		return flagEphemeral, nil
+	case conf.ZkNodeSequential:
+		return flagSequential, nil
+	case conf.ZkNodeEphemeralSequential:
+		return flagEphemeralSequential, nil
+	default:
+		return 0, fmt.Errorf("unknown zk node type: %s", nodeType)
+	}
+}
+
+func isEphemeral(flags int) bool {
+	return flags&flagEphemeral != 0
+}
+
+func churnParent() string {
+	return conf.ZkPath + "/churn"
+}
+
+func startChurn(client *zkClient) error {
+	flags, err := createFlags(conf.ZkNodeType)
+	if err != nil {
+		return err
+	}
+	if conf.ZkHoldNodes <= 0 {
+		return fmt.Errorf("invalid zk churn hold nodes: %d", conf.ZkHoldNodes)
+	}
+	resp, err := client.create(churnParent(), []byte(""), conf.ZkPermission, flagPersistent)
+	if err != nil {
+		return err
+	}
+	if resp.Error != codec.EC_OK && resp.Error != codec.EC_NodeExistsError {
+		return fmt.Errorf("create zk path %s error %d", churnParent(), resp.Error)
+	}
+	for i := 0; i < conf.RoutineNum; i++ {
+		go runChurn(flags)
+	}
+	return nil
+}
+
+// runChurn keeps opening churn sessions, the observer session outlives them to see the cleanup
+func runChurn(flags int) {
+	observer, err := newZkSession()
+	for err != nil {
+		logrus.Errorf("connect zk observer error: %v", err)
+		time.Sleep(time.Second)
+		observer, err = newZkSession()
+	}
+	for {
+		if err := churnSession(observer, flags); err != nil {
+			logrus.Errorf("zk churn session error: %v", err)
+			time.Sleep(time.Second)
+		}
+	}
+}
+
+// churnSession creates and deletes nodes in a directory of its own, then closes the session and
+// measures how long the server takes to remove the ephemeral nodes left
+func churnSession(observer *zkClient, flags int) error {
+	dir := churnParent() + "/" + uuid.NewString()
+	resp, err := observer.create(dir, []byte(""), conf.ZkPermission, flagPersistent)
+	if err != nil {
+		return err
+	}
+	if resp.Error != codec.EC_OK {
+		return fmt.Errorf("create zk path %s error %d", dir, resp.Error)
+	}
+	defer removeDir(observer, dir)
+	client, err := newZkSession()
+	if err != nil {
+		return err
+	}
+	sessionId, _ := client.session()
+	limiter := ratelimit.New(conf.RoutineRateLimit)
+	var held []string
+	for i := 0; conf.ZkSessionOps <= 0 || i < conf.ZkSessionOps; i++ {
+		limiter.Take()
+		// the ephemeral nodes held are gone with an expired session
+		if id, _ := client.session(); id != sessionId {
+			client.close()
+			return fmt.Errorf("churn session %d expired", sessionId)
+		}
+		if len(held) < conf.ZkHoldNodes {
+			if path, ok := churnCreate(client, dir+"/node-"+strconv.Itoa(i), flags); ok {
+				held = append(held, path)
+			}
+		} else if churnDelete(client, held[0]) {
+			held = held[1:]
+		}
+	}
+	if !isEphemeral(flags) {
+		for _, path := range held {
+			churnDelete(client, path)
+		}
+		return client.close()
+	}
+	closeAt := time.Now()
+	if err := client.close(); err != nil {
+		return err
+	}
+	return awaitCleanup(observer, dir, closeAt)
+}
+
+func churnCreate(client *zkClient, path string, flags int) (string, bool) {
+	start := time.Now()
+	resp, err := client.create(path, util.RandBytes(conf.DataSize), conf.ZkPermission, flags)
+	if err == nil && resp.Error != codec.EC_OK {
+		err = fmt.Errorf("error code %d", resp.Error)
+	}
+	if err != nil {
+		metrics.FailCount.WithLabelValues(conf.StorageTypeZooKeeper, conf.OperationTypeInsert).Inc()
+		logrus.Errorf("create zk path %s error %v", path, err)
+		return "", false
+	}
+	metrics.SuccessCount.WithLabelValues(conf.StorageTypeZooKeeper, conf.OperationTypeInsert).Inc()
+	metrics.SuccessLatency.WithLabelValues(conf.StorageTypeZooKeeper, conf.OperationTypeInsert).Observe(float64(time.Since(start)))
+	return resp.Path, true
+}
+
+func churnDelete(client *zkClient, path string) bool {
+	start := time.Now()
+	resp, err := client.delete(path, -1)
+	if err == nil && resp.Error != codec.EC_OK {
+		err = fmt.Errorf("error code %d", resp.Error)
+	}
+	if err != nil {
+		metrics.FailCount.WithLabelValues(conf.StorageTypeZooKeeper, conf.OperationTypeDelete).Inc()
+		logrus.Errorf("delete zk path %s error %v", path, err)
+		return false
+	}
+	metrics.SuccessCount.WithLabelValues(conf.StorageTypeZooKeeper, conf.OperationTypeDelete).Inc()
+	metrics.SuccessLatency.WithLabelValues(conf.StorageTypeZooKeeper, conf.OperationTypeDelete).Observe(float64(time.Since(start)))
+	return true
+}
+
+// removeDir deletes what is left in the directory of a churn session and then the directory, nodes
+// are left behind by a failed session, a failed delete or a cleanup that timed out
+func removeDir(observer *zkClient, dir string) {
+	resp, err := observer.getChildren(dir, false)
+	if err == nil && resp.Error != codec.EC_OK {
+		err = fmt.Errorf("error code %d", resp.Error)
+	}
+	if err != nil {
+		logrus.Errorf("get children %s error %v", dir, err)
+		return
+	}
+	for _, child := range resp.Children {
+		if err := removeNode(observer, dir+"/"+child); err != nil {
+			logrus.Errorf("remove zk path %s error %v", dir+"/"+child, err)
+		}
+	}
+	if err := removeNode(observer, dir); err != nil {
+		logrus.Errorf("remove zk path %s error %v", dir, err)
+	}
+}
+
+// removeNode ignores a node already gone, the server may remove an ephemeral node meanwhile
+func removeNode(client *zkClient, path string) error {
+	resp, err := client.delete(path, -1)
+	if err != nil {
+		return err
+	}
+	if resp.Error != codec.EC_OK && resp.Error != codec.EC_NoNodeError {
+		return fmt.Errorf("error code %d", resp.Error)
+	}
+	return nil
+}
+
+// awaitCleanup polls the children of dir until the ephemeral nodes of the closed session are gone
+func awaitCleanup(observer *zkClient, dir string, closeAt time.Time) error {
+	deadline := closeAt.Add(time.Millisecond * defaultTimeout)
+	for {
+		resp, err := observer.getChildren(dir, false)
+		if err != nil {
+			return err
+		}
+		if resp.Error != codec.EC_OK {
+			return fmt.Errorf("get children %s error %d", dir, resp.Error)
+		}
+		if len(resp.Children) == 0 {
+			metrics.ZkSessionCleanupLatency.Observe(float64(time.Since(closeAt).Microseconds()) / 1000)
+			return nil
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("%d ephemeral nodes of %s left after the session timeout", len(resp.Children), dir)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
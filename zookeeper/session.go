@@ -0,0 +1,164 @@
+// Licensed to the Apache Software Foundation (ASF) under one
+// or more contributor license agreements.  See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership.  The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package zookeeper
+
+import (
+	"errors"
+	"github.com/protocol-laboratory/zookeeper-codec-go/codec"
+	"github.com/sirupsen/logrus"
+	"perf-storage-go/metrics"
+	"sync/atomic"
+	"time"
+)
+
+// the session states of the metrics
+const (
+	sessionConnected    = "connected"
+	sessionDisconnected = "disconnected"
+)
+
+const (
+	reconnectBackoffMin = 100 * time.Millisecond
+	reconnectBackoffMax = 5 * time.Second
+)
+
+var errSessionExpired = errors.New("zookeeper session expired")
+
+// handshake opens a session on conn, or resumes the session of the client if it has one
+func (z *zkClient) handshake(conn *zkConn) error {
+	z.mutex.RLock()
+	req := &codec.ConnectReq{
+		ProtocolVersion: 0,
+		LastZxidSeen:    z.conn.zxid(),
+		Timeout:         defaultTimeout,
+		SessionId:       z.sessionId,
+		Password:        z.password,
+		ReadOnly:        false,
+	}
+	z.mutex.RUnlock()
+	resp, err := conn.connect(req)
+	if err != nil {
+		conn.close()
+		return err
+	}
+	// the server answers an expired session with a zero timeout and closes the connection
+	if resp.Timeout <= 0 {
+		conn.close()
+		return errSessionExpired
+	}
+	z.mutex.Lock()
+	defer z.mutex.Unlock()
+	if req.SessionId != 0 && req.SessionId != resp.SessionId {
+		logrus.Warnf("session id %d replaced by %d", req.SessionId, resp.SessionId)
+	}
+	atomic.CompareAndSwapInt64(&conn.lastZxid, 0, req.LastZxidSeen)
+	z.conn = conn
+	z.sessionId = resp.SessionId
+	z.password = resp.Password
+	z.sessionTimeout = time.Millisecond * time.Duration(resp.Timeout)
+	logrus.Info("session id is ", resp.SessionId)
+	return nil
+}
+
+func (z *zkClient) session() (int64, time.Duration) {
+	z.mutex.RLock()
+	defer z.mutex.RUnlock()
+	return z.sessionId, z.sessionTimeout
+}
+
+// keepAlive pings the server a few times per session timeout, a ping without reply drops the
+// connection, and a dropped connection is replaced until the client is closed
+func (z *zkClient) keepAlive() {
+	for {
+		conn := z.current()
+		_, timeout := z.session()
+		interval := timeout / 3
+		timer := time.NewTimer(interval)
+		select {
+		case <-z.closing:
+			timer.Stop()
+			metrics.ZkSessions.WithLabelValues(sessionConnected).Dec()
+			return
+		case <-conn.closed:
+			timer.Stop()
+			logrus.Warnf("zk connection lost: %v", conn.closeErr())
+			metrics.ZkSessions.WithLabelValues(sessionConnected).Dec()
+			metrics.ZkSessions.WithLabelValues(sessionDisconnected).Inc()
+			ok := z.reconnect()
+			metrics.ZkSessions.WithLabelValues(sessionDisconnected).Dec()
+			if !ok {
+				return
+			}
+			metrics.ZkSessions.WithLabelValues(sessionConnected).Inc()
+			select {
+			case z.reconnectCh <- struct{}{}:
+			default:
+			}
+		case <-timer.C:
+			start := time.Now()
+			if err := conn.ping(interval); err != nil {
+				logrus.Warnf("zk ping failed: %v", err)
+				conn.fail(err)
+				continue
+			}
+			metrics.ZkPingLatency.Observe(float64(time.Since(start).Microseconds()) / 1000)
+		}
+	}
+}
+
+// reconnect dials until the session is resumed or replaced by a new one, an expired session
+// loses its ephemeral nodes and watches, it returns false once the client is closed
+func (z *zkClient) reconnect() bool {
+	backoff := reconnectBackoffMin
+	expired := false
+	for {
+		select {
+		case <-z.closing:
+			return false
+		default:
+		}
+		conn, err := dialZk(z.host, z.port, time.Millisecond*defaultTimeout, z.eventCh)
+		if err == nil {
+			err = z.handshake(conn)
+		}
+		switch {
+		case err == nil && expired:
+			metrics.ZkReconnects.WithLabelValues("renewed").Inc()
+			return true
+		case err == nil:
+			metrics.ZkReconnects.WithLabelValues("resumed").Inc()
+			return true
+		case errors.Is(err, errSessionExpired):
+			expired = true
+			metrics.ZkReconnects.WithLabelValues("expired").Inc()
+			logrus.Warnf("zk session expired, open a new one")
+			z.mutex.Lock()
+			z.sessionId = 0
+			z.password = codec.PasswordEmpty
+			z.mutex.Unlock()
+			continue
+		default:
+			metrics.ZkReconnects.WithLabelValues("failed").Inc()
+			logrus.Errorf("zk reconnect failed: %v", err)
+		}
+		time.Sleep(backoff)
+		if backoff *= 2; backoff > reconnectBackoffMax {
+			backoff = reconnectBackoffMax
+		}
+	}
+}
@@ -0,0 +1,154 @@
+// Licensed to the Apache Software Foundation (ASF) under one
+// or more contributor license agreements.  See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership.  The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package zookeeper
+
+import (
+	"encoding/binary"
+	"github.com/protocol-laboratory/zookeeper-codec-go/codec"
+	"github.com/stretchr/testify/assert"
+	"net"
+	"testing"
+	"time"
+)
+
+// fakeSessionServer answers the connect requests with the next of timeouts, a zero timeout
+// expires the session, and replies to pings until the connection is dropped by the test
+func fakeSessionServer(t *testing.T, listener net.Listener, timeouts []int, conns chan<- net.Conn) {
+	var sessionId int64 = 7
+	for _, timeout := range timeouts {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		frame, err := readFrame(conn)
+		if err != nil {
+			return
+		}
+		req, err := codec.DecodeConnectReq(frame)
+		assert.Nil(t, err)
+		resp := &codec.ConnectResp{Timeout: timeout, Password: codec.PasswordEmpty}
+		if timeout > 0 {
+			resp.SessionId = req.SessionId
+			if resp.SessionId == 0 {
+				resp.SessionId = sessionId
+				sessionId++
+			}
+		}
+		conn.Write(resp.Bytes(true))
+		if timeout == 0 {
+			conn.Close()
+			continue
+		}
+		conns <- conn
+		go func() {
+			for {
+				frame, err := readFrame(conn)
+				if err != nil {
+					return
+				}
+				// reply to the ping with its own xid
+				reply := make([]byte, 4+16)
+				binary.BigEndian.PutUint32(reply, 16)
+				copy(reply[4:8], frame[:4])
+				conn.Write(reply)
+			}
+		}()
+	}
+}
+
+func TestSessionReconnect(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Nil(t, err)
+	defer listener.Close()
+	conns := make(chan net.Conn, 3)
+	go fakeSessionServer(t, listener, []int{300, 300, 0, 300}, conns)
+
+	port := listener.Addr().(*net.TCPAddr).Port
+	client, err := newZkClient("127.0.0.1", port)
+	assert.Nil(t, err)
+	assert.Nil(t, client.connect())
+	defer client.close()
+	sessionId, timeout := client.session()
+	assert.Equal(t, int64(7), sessionId)
+	assert.Equal(t, 300*time.Millisecond, timeout)
+
+	// pings keep the connection alive for a few session timeouts
+	first := <-conns
+	time.Sleep(time.Second)
+	select {
+	case <-client.current().closed:
+		t.Fatal("connection dropped while pinging")
+	default:
+	}
+
+	// the session is resumed on a new connection
+	first.Close()
+	<-client.reconnected()
+	sessionId, _ = client.session()
+	assert.Equal(t, int64(7), sessionId)
+
+	// the session expires, a new one is opened
+	second := <-conns
+	second.Close()
+	<-client.reconnected()
+	sessionId, _ = client.session()
+	assert.Equal(t, int64(8), sessionId)
+}
+
+func TestNextXid(t *testing.T) {
+	client := &zkClient{transactionId: 1<<31 - 1}
+	assert.Equal(t, 1<<31-1, client.nextXid())
+	// the wrapped counter never hands out the reserved negative xids
+	assert.Equal(t, 0, client.nextXid())
+	assert.Equal(t, 1, client.nextXid())
+}
+
+func TestLostPing(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Nil(t, err)
+	defer listener.Close()
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		if _, err := readFrame(conn); err != nil {
+			return
+		}
+		conn.Write((&codec.ConnectResp{Timeout: 300, SessionId: 7, Password: codec.PasswordEmpty}).Bytes(true))
+		// the pings are never answered
+		for {
+			if _, err := readFrame(conn); err != nil {
+				return
+			}
+		}
+	}()
+
+	port := listener.Addr().(*net.TCPAddr).Port
+	client, err := newZkClient("127.0.0.1", port)
+	assert.Nil(t, err)
+	assert.Nil(t, client.connect())
+	defer client.close()
+	// the ping of the 300ms session is sent after 100ms and given up after another 100ms
+	select {
+	case <-client.current().closed:
+	case <-time.After(time.Second):
+		t.Fatal("unanswered ping not noticed within the session timeout")
+	}
+}
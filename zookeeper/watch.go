@@ -0,0 +1,349 @@
+// Licensed to the Apache Software Foundation (ASF) under one
+// or more contributor license agreements.  See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership.  The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package zookeeper
+
+import (
+	"fmt"
+	"github.com/protocol-laboratory/zookeeper-codec-go/codec"
+	"github.com/sirupsen/logrus"
+	"go.uber.org/ratelimit"
+	"math/rand"
+	"perf-storage-go/conf"
+	"perf-storage-go/metrics"
+	"perf-storage-go/util"
+	"sync"
+	"time"
+)
+
+const (
+	watchTypeData  = "data"
+	watchTypeChild = "child"
+)
+
+const (
+	// ackHistory is how many versions of a node the tracker remembers
+	ackHistory = 1024
+)
+
+// the reasons an event gets no watch latency
+const (
+	// unmatchedNoWrite no write of the version was sent by a writer, e.g. another process changed the node
+	unmatchedNoWrite = "no_write"
+	// unmatchedBeforeAck the event arrived before the writer got the response of the write
+	unmatchedBeforeAck = "before_ack"
+)
+
+type ackKey struct {
+	path      string
+	watchType string
+	version   int
+}
+
+// writeTimes are when a write was sent and when its response reached the writer, zero until then
+type writeTimes struct {
+	sentAt time.Time
+	ackAt  time.Time
+}
+
+// ackTracker remembers when the writes of the watched nodes were sent and acknowledged to the writers
+type ackTracker struct {
+	mutex sync.RWMutex
+	acks  map[ackKey]writeTimes
+}
+
+func newAckTracker() *ackTracker {
+	return &ackTracker{acks: make(map[ackKey]writeTimes)}
+}
+
+// sent is recorded before the write is sent, so the event of the write always finds it
+func (t *ackTracker) sent(path string, watchType string, version int, sentAt time.Time) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	t.acks[ackKey{path, watchType, version}] = writeTimes{sentAt: sentAt}
+	delete(t.acks, ackKey{path, watchType, version - ackHistory})
+}
+
+func (t *ackTracker) acked(path string, watchType string, version int, ackAt time.Time) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	key := ackKey{path, watchType, version}
+	times := t.acks[key]
+	times.ackAt = ackAt
+	t.acks[key] = times
+}
+
+func (t *ackTracker) get(path string, watchType string, version int) (writeTimes, bool) {
+	t.mutex.RLock()
+	defer t.mutex.RUnlock()
+	times, ok := t.acks[ackKey{path, watchType, version}]
+	return times, ok
+}
+
+func watchNodePath(i int) string {
+	return fmt.Sprintf("%s/watch-%d", conf.ZkPath, i)
+}
+
+func watchData() bool {
+	return conf.ZkWatchType != conf.ZkWatchChild
+}
+
+func watchChild() bool {
+	return conf.ZkWatchType != conf.ZkWatchData
+}
+
+// startWatch creates the watched nodes and starts the writers and the watchers
+func startWatch(client *zkClient) error {
+	for i := 0; i < conf.ZkWatchNodes; i++ {
+		path := watchNodePath(i)
+		resp, err := client.create(path, []byte(""), conf.ZkPermission, flagPersistent)
+		if err != nil {
+			return err
+		}
+		if resp.Error != codec.EC_OK && resp.Error != codec.EC_NodeExistsError {
+			return fmt.Errorf("create zk path %s error %d", path, resp.Error)
+		}
+	}
+	tracker := newAckTracker()
+	for i := 0; i < conf.ZkWatcherNum; i++ {
+		go runWatcher(tracker)
+	}
+	for i := 0; i < conf.ZkWriterNum; i++ {
+		var index = i
+		go runWriter(tracker, index)
+	}
+	return nil
+}
+
+// watchedNode holds the versions a watcher saw when it set its watches
+type watchedNode struct {
+	dataVersion  int
+	childVersion int
+}
+
+func runWatcher(tracker *ackTracker) {
+	client, err := newZkSession()
+	if err != nil {
+		logrus.Errorf("connect zk watcher error: %v", err)
+		return
+	}
+	nodes := make(map[string]*watchedNode, conf.ZkWatchNodes)
+	if err := watchNodes(client, nodes); err != nil {
+		logrus.Errorf("set zk watches error: %v", err)
+		return
+	}
+	for {
+		select {
+		case event := <-client.events():
+			node, ok := nodes[event.Path]
+			if !ok {
+				continue
+			}
+			switch event.Type {
+			case eventNodeDataChanged:
+				node.dataVersion, err = onWatchEvent(tracker, event, watchTypeData, node.dataVersion, client.watchData)
+			case eventNodeChildrenChanged:
+				node.childVersion, err = onWatchEvent(tracker, event, watchTypeChild, node.childVersion, client.watchChildren)
+			}
+			if err != nil {
+				logrus.Errorf("watch %s again error: %v", event.Path, err)
+				return
+			}
+		case <-client.reconnected():
+			// the watches are not carried over to the new connection
+			if err := watchNodes(client, nodes); err != nil {
+				logrus.Errorf("set zk watches after reconnect error: %v", err)
+				return
+			}
+		case <-client.done():
+			return
+		}
+	}
+}
+
+// watchNodes sets the watches on every watched node and records the versions they watch, when
+// watching again the changes since the last versions watched are counted as missed
+func watchNodes(client *zkClient, nodes map[string]*watchedNode) error {
+	var err error
+	for i := 0; i < conf.ZkWatchNodes; i++ {
+		path := watchNodePath(i)
+		last, watched := nodes[path]
+		node := &watchedNode{}
+		if watchData() {
+			if node.dataVersion, err = client.watchData(path); err != nil {
+				return fmt.Errorf("watch data of %s: %w", path, err)
+			}
+		}
+		if watchChild() {
+			if node.childVersion, err = client.watchChildren(path); err != nil {
+				return fmt.Errorf("watch children of %s: %w", path, err)
+			}
+		}
+		if watched {
+			if missed := node.dataVersion - last.dataVersion; missed > 0 {
+				metrics.ZkMissedEvents.WithLabelValues(watchTypeData).Add(float64(missed))
+			}
+			if missed := node.childVersion - last.childVersion; missed > 0 {
+				metrics.ZkMissedEvents.WithLabelValues(watchTypeChild).Add(float64(missed))
+			}
+		}
+		nodes[path] = node
+	}
+	return nil
+}
+
+// onWatchEvent observes the event of the write after the watched version, sets the watch again
+// and counts the writes in between whose events were missed, it returns the version now watched
+func onWatchEvent(tracker *ackTracker, event watchEvent, watchType string, version int, watch func(string) (int, error)) (int, error) {
+	metrics.ZkWatchEvents.WithLabelValues(watchType).Inc()
+	observeEvent(tracker, event, watchType, version+1)
+	current, err := watch(event.Path)
+	if err != nil {
+		return version, err
+	}
+	if missed := current - version - 1; missed > 0 {
+		metrics.ZkMissedEvents.WithLabelValues(watchType).Add(float64(missed))
+	}
+	return current, nil
+}
+
+// observeEvent never waits for the writer, ZooKeeper triggers the watches before it responds to the
+// write so the latency from the send is always taken and the one from the ack only when it came first
+func observeEvent(tracker *ackTracker, event watchEvent, watchType string, version int) {
+	times, ok := tracker.get(event.Path, watchType, version)
+	if !ok {
+		metrics.ZkUnmatchedEvents.WithLabelValues(watchType, unmatchedNoWrite).Inc()
+		return
+	}
+	metrics.ZkWatchSendLatency.WithLabelValues(watchType).Observe(float64(event.ReceivedAt.Sub(times.sentAt).Microseconds()) / 1000)
+	if times.ackAt.IsZero() || event.ReceivedAt.Before(times.ackAt) {
+		metrics.ZkUnmatchedEvents.WithLabelValues(watchType, unmatchedBeforeAck).Inc()
+		return
+	}
+	metrics.ZkWatchLatency.WithLabelValues(watchType).Observe(float64(event.ReceivedAt.Sub(times.ackAt).Microseconds()) / 1000)
+}
+
+// runWriter changes the watched nodes owned by this writer, one writer per node keeps the
+// versions known without reading them back, they are read again after a failed write which may
+// have been applied anyway
+func runWriter(tracker *ackTracker, index int) {
+	client, err := newZkSession()
+	if err != nil {
+		logrus.Errorf("connect zk writer error: %v", err)
+		return
+	}
+	var paths []string
+	nodes := make(map[string]*writtenNode)
+	for i := index; i < conf.ZkWatchNodes; i += conf.ZkWriterNum {
+		path := watchNodePath(i)
+		node := &writtenNode{}
+		if err := node.read(client, path); err != nil {
+			logrus.Errorf("read zk path %s error %v", path, err)
+			return
+		}
+		paths = append(paths, path)
+		nodes[path] = node
+	}
+	if len(paths) == 0 {
+		return
+	}
+	limiter := ratelimit.New(conf.RoutineRateLimit)
+	for {
+		limiter.Take()
+		path := paths[rand.Intn(len(paths))]
+		node := nodes[path]
+		if watchData() && (!watchChild() || rand.Intn(2) == 0) {
+			start := time.Now()
+			tracker.sent(path, watchTypeData, node.dataVersion+1, start)
+			resp, err := client.setData(path, util.RandBytes(conf.DataSize), -1)
+			if err == nil && resp.Error != codec.EC_OK {
+				err = fmt.Errorf("error code %d", resp.Error)
+			}
+			if err != nil {
+				metrics.FailCount.WithLabelValues(conf.StorageTypeZooKeeper, conf.OperationTypeUpdate).Inc()
+				logrus.Errorf("set zk path %s error %v", path, err)
+				node.resync(client, path)
+				continue
+			}
+			ackAt := time.Now()
+			if resp.Stat.Version != node.dataVersion+1 {
+				// the node was written by someone else meanwhile
+				tracker.sent(path, watchTypeData, resp.Stat.Version, start)
+			}
+			node.dataVersion = resp.Stat.Version
+			tracker.acked(path, watchTypeData, node.dataVersion, ackAt)
+			metrics.SuccessCount.WithLabelValues(conf.StorageTypeZooKeeper, conf.OperationTypeUpdate).Inc()
+			metrics.SuccessLatency.WithLabelValues(conf.StorageTypeZooKeeper, conf.OperationTypeUpdate).Observe(float64(time.Since(start)))
+			continue
+		}
+		child := path + "/child"
+		opType := conf.OperationTypeInsert
+		start := time.Now()
+		tracker.sent(path, watchTypeChild, node.childVersion+1, start)
+		var code codec.ErrorCode
+		if node.hasChild {
+			opType = conf.OperationTypeDelete
+			var resp *codec.DeleteResp
+			if resp, err = client.delete(child, -1); err == nil {
+				code = resp.Error
+			}
+		} else {
+			var resp *codec.CreateResp
+			if resp, err = client.create(child, []byte(""), conf.ZkPermission, flagPersistent); err == nil {
+				code = resp.Error
+			}
+		}
+		if err != nil || code != codec.EC_OK {
+			metrics.FailCount.WithLabelValues(conf.StorageTypeZooKeeper, opType).Inc()
+			logrus.Errorf("change children of zk path %s error %v, code %d", path, err, code)
+			node.resync(client, path)
+			continue
+		}
+		node.hasChild = !node.hasChild
+		node.childVersion++
+		tracker.acked(path, watchTypeChild, node.childVersion, time.Now())
+		metrics.SuccessCount.WithLabelValues(conf.StorageTypeZooKeeper, opType).Inc()
+		metrics.SuccessLatency.WithLabelValues(conf.StorageTypeZooKeeper, opType).Observe(float64(time.Since(start)))
+	}
+}
+
+// writtenNode is what a writer knows of a node it owns
+type writtenNode struct {
+	dataVersion  int
+	childVersion int
+	hasChild     bool
+}
+
+func (n *writtenNode) read(client *zkClient, path string) error {
+	resp, err := client.exists(path, false)
+	if err != nil {
+		return err
+	}
+	if resp.Error != codec.EC_OK {
+		return fmt.Errorf("error code %d", resp.Error)
+	}
+	n.dataVersion = resp.Stat.Version
+	n.childVersion = resp.Stat.ChildVersion
+	n.hasChild = resp.Stat.NumChildren > 0
+	return nil
+}
+
+func (n *writtenNode) resync(client *zkClient, path string) {
+	if err := n.read(client, path); err != nil {
+		logrus.Errorf("read zk path %s error %v", path, err)
+	}
+}
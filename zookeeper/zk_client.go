@@ -20,74 +20,209 @@ package zookeeper
 import (
 	"fmt"
 	"github.com/protocol-laboratory/zookeeper-codec-go/codec"
-	"github.com/protocol-laboratory/zookeeper-codec-go/zknet"
-	"github.com/sirupsen/logrus"
+	"math"
+	"perf-storage-go/conf"
+	"perf-storage-go/metrics"
+	"sync"
+	"sync/atomic"
+	"time"
 )
 
 const defaultTimeout = 30_000
 
+// zkClient is safe for concurrent use, the session survives connection losses, see session.go
 type zkClient struct {
-	netClient     *zknet.ZookeeperNetClient
-	transactionId int
+	host          string
+	port          int
+	transactionId int32
+	mutex         sync.RWMutex
+	conn          *zkConn
+	sessionId     int64
+	password      []byte
+	// sessionTimeout is the timeout negotiated with the server
+	sessionTimeout time.Duration
+	eventCh        chan watchEvent
+	reconnectCh    chan struct{}
+	closing        chan struct{}
+	closeOnce      sync.Once
 }
 
+// connect opens the session and keeps it alive until close
 func (z *zkClient) connect() error {
-	resp, err := z.netClient.Connect(&codec.ConnectReq{
-		ProtocolVersion: 0,
-		LastZxidSeen:    0,
-		Timeout:         defaultTimeout,
-		SessionId:       0,
-		Password:        codec.PasswordEmpty,
-		ReadOnly:        false,
-	})
-	if err != nil {
+	if err := z.handshake(z.current()); err != nil {
 		return err
 	}
-	logrus.Info("session id is ", resp.SessionId)
+	metrics.ZkSessions.WithLabelValues(sessionConnected).Inc()
+	go z.keepAlive()
 	return nil
 }
 
-func (z *zkClient) create(path string, val []byte, permission int) (*codec.CreateResp, error) {
-	resp, err := z.netClient.Create(&codec.CreateReq{
-		TransactionId: z.transactionId,
+func (z *zkClient) current() *zkConn {
+	z.mutex.RLock()
+	defer z.mutex.RUnlock()
+	return z.conn
+}
+
+// nextXid skips the negative xids reserved for notifications and pings once the counter wraps
+func (z *zkClient) nextXid() int {
+	return int((atomic.AddInt32(&z.transactionId, 1) - 1) & math.MaxInt32)
+}
+
+func (z *zkClient) send(xid int, bytes []byte) ([]byte, error) {
+	return z.current().send(xid, bytes)
+}
+
+// the create flags, see org.apache.zookeeper.CreateMode
+const (
+	flagPersistent          = 0
+	flagEphemeral           = 1
+	flagSequential          = 2
+	flagEphemeralSequential = 3
+)
+
+// create returns the created path in the response, a sequential node gets a counter appended
+func (z *zkClient) create(path string, val []byte, permission int, flags int) (*codec.CreateResp, error) {
+	req := &codec.CreateReq{
+		TransactionId: z.nextXid(),
 		OpCode:        codec.OP_CREATE,
 		Path:          path,
 		Data:          []byte(val),
 		Permissions:   []int{permission},
 		Scheme:        "world",
 		Credentials:   "anyone",
-		Flags:         0,
-	})
-	z.transactionId += 1
-	return resp, err
+		Flags:         flags,
+	}
+	bytes, err := z.send(req.TransactionId, req.Bytes(true))
+	if err != nil {
+		return nil, err
+	}
+	return codec.DecodeCreateResp(bytes)
+}
+
+func (z *zkClient) delete(path string, version int) (*codec.DeleteResp, error) {
+	req := &codec.DeleteReq{
+		TransactionId: z.nextXid(),
+		OpCode:        codec.OP_DELETE,
+		Path:          path,
+		Version:       version,
+	}
+	bytes, err := z.send(req.TransactionId, req.Bytes(true))
+	if err != nil {
+		return nil, err
+	}
+	return codec.DecodeDeleteResp(bytes)
 }
 
-func (z *zkClient) exists(path string) (*codec.ExistsResp, error) {
-	resp, err := z.netClient.Exists(&codec.ExistsReq{
-		TransactionId: z.transactionId,
+// exists sets a data watch on path when watch is true, it fires on the next change or creation
+func (z *zkClient) exists(path string, watch bool) (*codec.ExistsResp, error) {
+	req := &codec.ExistsReq{
+		TransactionId: z.nextXid(),
 		OpCode:        codec.OP_EXISTS,
 		Path:          path,
-		Watch:         true,
-	})
-	z.transactionId += 1
-	return resp, err
+		Watch:         watch,
+	}
+	bytes, err := z.send(req.TransactionId, req.Bytes(true))
+	if err != nil {
+		return nil, err
+	}
+	return codec.DecodeExistsResp(bytes)
 }
 
-func (z *zkClient) getChildren(path string) (*codec.GetChildrenResp, error) {
-	resp, err := z.netClient.GetChildren(&codec.GetChildrenReq{
-		TransactionId: z.transactionId,
-		OpCode:        codec.OP_GET_DATA,
+func (z *zkClient) setData(path string, val []byte, version int) (*codec.SetDataResp, error) {
+	req := &codec.SetDataReq{
+		TransactionId: z.nextXid(),
+		OpCode:        codec.OP_SET_DATA,
 		Path:          path,
-		Watch:         true,
-	})
-	z.transactionId += 1
-	return resp, err
+		Data:          val,
+		Version:       version,
+	}
+	bytes, err := z.send(req.TransactionId, req.Bytes(true))
+	if err != nil {
+		return nil, err
+	}
+	// the codec decodes a stat even for an error response
+	if code := replyError(bytes); code != codec.EC_OK {
+		return &codec.SetDataResp{Error: code}, nil
+	}
+	return codec.DecodeSetDataResp(bytes)
+}
+
+// getChildren sets a child watch on path when watch is true
+func (z *zkClient) getChildren(path string, watch bool) (*codec.GetChildrenResp, error) {
+	req := &codec.GetChildrenReq{
+		TransactionId: z.nextXid(),
+		OpCode:        codec.OP_GET_CHILDREN,
+		Path:          path,
+		Watch:         watch,
+	}
+	bytes, err := z.send(req.TransactionId, req.Bytes(true))
+	if err != nil {
+		return nil, err
+	}
+	return codec.DecodeGetChildrenResp(bytes)
+}
+
+// watchData sets a data watch on path and returns the data version it watches
+func (z *zkClient) watchData(path string) (int, error) {
+	resp, err := z.exists(path, true)
+	if err != nil {
+		return 0, err
+	}
+	if resp.Error != codec.EC_OK {
+		return 0, fmt.Errorf("exists %s error %d", path, resp.Error)
+	}
+	return resp.Stat.Version, nil
+}
+
+// watchChildren sets a child watch on path and returns the child version it watches, the version
+// is read after the watch is set so a change in between is seen by both
+func (z *zkClient) watchChildren(path string) (int, error) {
+	resp, err := z.getChildren(path, true)
+	if err != nil {
+		return 0, err
+	}
+	if resp.Error != codec.EC_OK {
+		return 0, fmt.Errorf("get children %s error %d", path, resp.Error)
+	}
+	existsResp, err := z.exists(path, false)
+	if err != nil {
+		return 0, err
+	}
+	if existsResp.Error != codec.EC_OK {
+		return 0, fmt.Errorf("exists %s error %d", path, existsResp.Error)
+	}
+	return existsResp.Stat.ChildVersion, nil
+}
+
+// events delivers the watch notifications of the session
+func (z *zkClient) events() <-chan watchEvent {
+	return z.eventCh
+}
+
+// reconnected receives after the session moved to a new connection, the watches have to be set again
+func (z *zkClient) reconnected() <-chan struct{} {
+	return z.reconnectCh
+}
+
+// done is closed once the client is closed
+func (z *zkClient) done() <-chan struct{} {
+	return z.closing
 }
 
 func (z *zkClient) close() error {
-	closeResp, err := z.netClient.CloseSession(&codec.CloseReq{
-		TransactionId: z.transactionId,
+	z.closeOnce.Do(func() {
+		close(z.closing)
 	})
+	defer z.current().close()
+	req := &codec.CloseReq{
+		TransactionId: z.nextXid(),
+		OpCode:        codec.OP_CLOSE_SESSION,
+	}
+	bytes, err := z.send(req.TransactionId, req.Bytes(true))
+	if err != nil {
+		return err
+	}
+	closeResp, err := codec.DecodeCloseResp(bytes)
 	if err != nil {
 		return err
 	}
@@ -97,17 +232,35 @@ func (z *zkClient) close() error {
 	return nil
 }
 
+// newZkSession connects a new client to ZK_HOST
+func newZkSession() (*zkClient, error) {
+	client, err := newZkClient(conf.ZkHost, conf.ZkPort)
+	if err != nil {
+		return nil, err
+	}
+	if err := client.connect(); err != nil {
+		client.current().close()
+		return nil, err
+	}
+	return client, nil
+}
+
 func newZkClient(host string, port int) (*zkClient, error) {
-	zkNetClient, err := zknet.NewZkNetClient(zknet.ZookeeperNetClientConfig{
-		Host: host,
-		Port: port,
-	})
+	events := make(chan watchEvent, 4096)
+	conn, err := dialZk(host, port, time.Millisecond*defaultTimeout, events)
 	if err != nil {
 		return nil, err
 	}
 	zkClient := &zkClient{
-		transactionId: 0,
+		host:           host,
+		port:           port,
+		transactionId:  0,
+		password:       codec.PasswordEmpty,
+		sessionTimeout: time.Millisecond * defaultTimeout,
+		eventCh:        events,
+		reconnectCh:    make(chan struct{}, 1),
+		closing:        make(chan struct{}),
 	}
-	zkClient.netClient = zkNetClient
+	zkClient.conn = conn
 	return zkClient, nil
 }
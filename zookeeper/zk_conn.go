@@ -0,0 +1,276 @@
+// Licensed to the Apache Software Foundation (ASF) under one
+// or more contributor license agreements.  See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership.  The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package zookeeper
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"github.com/protocol-laboratory/zookeeper-codec-go/codec"
+	"io"
+	"net"
+	"perf-storage-go/metrics"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// the xids the server uses for notifications and ping replies, a ping is sent with pingXid
+const (
+	notificationXid = -1
+	pingXid         = -2
+)
+
+// the watch event types, see org.apache.zookeeper.Watcher.Event.EventType
+const (
+	eventNodeCreated         = 1
+	eventNodeDeleted         = 2
+	eventNodeDataChanged     = 3
+	eventNodeChildrenChanged = 4
+)
+
+const maxFrameSize = 4 * 1024 * 1024
+
+var errConnClosed = errors.New("zookeeper connection closed")
+
+// watchEvent is a fired watch, the watch has to be set again to see the next change
+type watchEvent struct {
+	Type       int32
+	State      int32
+	Path       string
+	ReceivedAt time.Time
+}
+
+// zkConn multiplexes the requests of a session over one connection, the responses are matched
+// to the requests by xid and the watch notifications are handed over through events
+type zkConn struct {
+	conn      net.Conn
+	timeout   time.Duration
+	writeLock sync.Mutex
+	mutex     sync.Mutex
+	pending   map[int32]chan []byte
+	events    chan watchEvent
+	closed    chan struct{}
+	closeOnce sync.Once
+	err       error
+	// lastZxid is the last zxid the server replied with, a resumed session must not go back in time
+	lastZxid int64
+}
+
+func dialZk(host string, port int, timeout time.Duration, events chan watchEvent) (*zkConn, error) {
+	conn, err := net.DialTimeout("tcp", fmt.Sprintf("%s:%d", host, port), timeout)
+	if err != nil {
+		return nil, err
+	}
+	return newZkConn(conn, timeout, events), nil
+}
+
+// newZkConn hands the notifications over to events, which outlives the connection when the
+// session moves to a new one
+func newZkConn(conn net.Conn, timeout time.Duration, events chan watchEvent) *zkConn {
+	return &zkConn{
+		conn:    conn,
+		timeout: timeout,
+		pending: make(map[int32]chan []byte),
+		events:  events,
+		closed:  make(chan struct{}),
+	}
+}
+
+// connect sends the connect request, whose response carries no xid, and starts reading
+func (c *zkConn) connect(req *codec.ConnectReq) (*codec.ConnectResp, error) {
+	if err := c.write(req.Bytes(true)); err != nil {
+		return nil, err
+	}
+	c.conn.SetReadDeadline(time.Now().Add(c.timeout))
+	frame, err := readFrame(c.conn)
+	c.conn.SetReadDeadline(time.Time{})
+	if err != nil {
+		return nil, err
+	}
+	resp, err := codec.DecodeConnectResp(frame)
+	if err != nil {
+		return nil, err
+	}
+	go c.readLoop()
+	return resp, nil
+}
+
+// send writes a request framed by the codec and waits for the response with the same xid
+func (c *zkConn) send(xid int, bytes []byte) ([]byte, error) {
+	return c.sendWithin(xid, bytes, c.timeout)
+}
+
+func (c *zkConn) sendWithin(xid int, bytes []byte, timeout time.Duration) ([]byte, error) {
+	ch := make(chan []byte, 1)
+	c.mutex.Lock()
+	if c.err != nil {
+		c.mutex.Unlock()
+		return nil, c.err
+	}
+	c.pending[int32(xid)] = ch
+	c.mutex.Unlock()
+	defer func() {
+		c.mutex.Lock()
+		delete(c.pending, int32(xid))
+		c.mutex.Unlock()
+	}()
+	if err := c.write(bytes); err != nil {
+		c.fail(err)
+		return nil, err
+	}
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	select {
+	case frame := <-ch:
+		return frame, nil
+	case <-c.closed:
+		return nil, c.closeErr()
+	case <-timer.C:
+		return nil, fmt.Errorf("request %d timed out after %v", xid, timeout)
+	}
+}
+
+// ping sends a heartbeat, the reply is matched by pingXid so only one ping may be in flight, it has
+// to be answered within timeout, well below the session timeout so the session can still be resumed
+func (c *zkConn) ping(timeout time.Duration) error {
+	var xid int32 = pingXid
+	bytes := make([]byte, 12)
+	binary.BigEndian.PutUint32(bytes, 8)
+	binary.BigEndian.PutUint32(bytes[4:], uint32(xid))
+	binary.BigEndian.PutUint32(bytes[8:], uint32(codec.OP_PING))
+	_, err := c.sendWithin(pingXid, bytes, timeout)
+	return err
+}
+
+func (c *zkConn) zxid() int64 {
+	return atomic.LoadInt64(&c.lastZxid)
+}
+
+func (c *zkConn) write(bytes []byte) error {
+	c.writeLock.Lock()
+	defer c.writeLock.Unlock()
+	_, err := c.conn.Write(bytes)
+	return err
+}
+
+func (c *zkConn) readLoop() {
+	for {
+		frame, err := readFrame(c.conn)
+		if err != nil {
+			c.fail(err)
+			return
+		}
+		if len(frame) < 4 {
+			c.fail(fmt.Errorf("frame of %d bytes has no xid", len(frame)))
+			return
+		}
+		xid := int32(binary.BigEndian.Uint32(frame))
+		if len(frame) >= 12 {
+			if zxid := int64(binary.BigEndian.Uint64(frame[4:])); zxid > 0 {
+				atomic.StoreInt64(&c.lastZxid, zxid)
+			}
+		}
+		switch xid {
+		case notificationXid:
+			event, err := decodeWatchEvent(frame)
+			if err != nil {
+				c.fail(err)
+				return
+			}
+			select {
+			case c.events <- event:
+			default:
+				metrics.ZkDroppedEvents.Inc()
+			}
+		default:
+			c.mutex.Lock()
+			ch, ok := c.pending[xid]
+			c.mutex.Unlock()
+			if ok {
+				ch <- frame
+			}
+		}
+	}
+}
+
+func (c *zkConn) fail(err error) {
+	c.closeOnce.Do(func() {
+		c.mutex.Lock()
+		c.err = err
+		c.mutex.Unlock()
+		close(c.closed)
+		c.conn.Close()
+	})
+}
+
+func (c *zkConn) closeErr() error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.err
+}
+
+func (c *zkConn) close() {
+	c.fail(errConnClosed)
+}
+
+// replyError reads the error code of the reply header, xid(4) zxid(8) err(4)
+func replyError(frame []byte) codec.ErrorCode {
+	if len(frame) < 16 {
+		return codec.EC_OK
+	}
+	return codec.ErrorCode(int32(binary.BigEndian.Uint32(frame[12:16])))
+}
+
+func readFrame(r io.Reader) ([]byte, error) {
+	var header [4]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		return nil, err
+	}
+	length := binary.BigEndian.Uint32(header[:])
+	if length > maxFrameSize {
+		return nil, fmt.Errorf("frame length %d is too large", length)
+	}
+	frame := make([]byte, length)
+	if _, err := io.ReadFull(r, frame); err != nil {
+		return nil, err
+	}
+	return frame, nil
+}
+
+// decodeWatchEvent decodes the reply header and the WatcherEvent of a notification
+func decodeWatchEvent(frame []byte) (watchEvent, error) {
+	const headerLen = 4 + 8 + 4
+	if len(frame) < headerLen+12 {
+		return watchEvent{}, fmt.Errorf("notification of %d bytes is too short", len(frame))
+	}
+	body := frame[headerLen:]
+	event := watchEvent{
+		Type:       int32(binary.BigEndian.Uint32(body)),
+		State:      int32(binary.BigEndian.Uint32(body[4:])),
+		ReceivedAt: time.Now(),
+	}
+	pathLen := int32(binary.BigEndian.Uint32(body[8:]))
+	if pathLen > 0 {
+		if int(pathLen) > len(body)-12 {
+			return watchEvent{}, fmt.Errorf("notification path of %d bytes exceeds the frame", pathLen)
+		}
+		event.Path = string(body[12 : 12+pathLen])
+	}
+	return event, nil
+}
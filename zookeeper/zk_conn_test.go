@@ -0,0 +1,147 @@
+// Licensed to the Apache Software Foundation (ASF) under one
+// or more contributor license agreements.  See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership.  The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package zookeeper
+
+import (
+	"encoding/binary"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/protocol-laboratory/zookeeper-codec-go/codec"
+	"github.com/stretchr/testify/assert"
+	"net"
+	"perf-storage-go/metrics"
+	"testing"
+	"time"
+)
+
+func frame(body []byte) []byte {
+	bytes := make([]byte, 4+len(body))
+	binary.BigEndian.PutUint32(bytes, uint32(len(body)))
+	copy(bytes[4:], body)
+	return bytes
+}
+
+func notification(eventType int32, path string) []byte {
+	body := make([]byte, 4+8+4+4+4+4+len(path))
+	binary.BigEndian.PutUint32(body, uint32(0xffffffff))
+	binary.BigEndian.PutUint64(body[4:], uint64(0xffffffffffffffff))
+	binary.BigEndian.PutUint32(body[16:], uint32(eventType))
+	binary.BigEndian.PutUint32(body[20:], 3)
+	binary.BigEndian.PutUint32(body[24:], uint32(len(path)))
+	copy(body[28:], path)
+	return frame(body)
+}
+
+func TestZkConn(t *testing.T) {
+	client, server := net.Pipe()
+	conn := newZkConn(client, time.Second, make(chan watchEvent, 16))
+	defer conn.close()
+
+	go func() {
+		if _, err := readFrame(server); err != nil {
+			return
+		}
+		server.Write((&codec.ConnectResp{Timeout: 30_000, SessionId: 7, Password: codec.PasswordEmpty}).Bytes(true))
+		// two requests answered in reverse order with a notification in between
+		for i := 0; i < 2; i++ {
+			if _, err := readFrame(server); err != nil {
+				return
+			}
+		}
+		server.Write(frame((&codec.CreateResp{TransactionId: 2, Path: "/b"}).Bytes()))
+		server.Write(notification(eventNodeDataChanged, "/perf/watch-0"))
+		server.Write(frame((&codec.CreateResp{TransactionId: 1, Path: "/a"}).Bytes()))
+	}()
+
+	resp, err := conn.connect(&codec.ConnectReq{Timeout: 30_000, Password: codec.PasswordEmpty})
+	assert.Nil(t, err)
+	assert.Equal(t, int64(7), resp.SessionId)
+
+	results := make(chan string, 2)
+	for _, xid := range []int{1, 2} {
+		var req = &codec.CreateReq{TransactionId: xid, Path: "/x", Permissions: []int{31}}
+		go func() {
+			bytes, err := conn.send(req.TransactionId, req.Bytes(true))
+			assert.Nil(t, err)
+			createResp, err := codec.DecodeCreateResp(bytes)
+			assert.Nil(t, err)
+			assert.Equal(t, req.TransactionId, createResp.TransactionId)
+			results <- createResp.Path
+		}()
+		// keep the write order of the requests
+		time.Sleep(10 * time.Millisecond)
+	}
+	assert.ElementsMatch(t, []string{"/a", "/b"}, []string{<-results, <-results})
+
+	event := <-conn.events
+	assert.Equal(t, int32(eventNodeDataChanged), event.Type)
+	assert.Equal(t, "/perf/watch-0", event.Path)
+
+	server.Close()
+	<-conn.closed
+	_, err = conn.send(3, (&codec.CloseReq{TransactionId: 3}).Bytes(true))
+	assert.NotNil(t, err)
+}
+
+func TestAckTracker(t *testing.T) {
+	tracker := newAckTracker()
+	sentAt := time.Now()
+	tracker.sent("/perf/watch-0", watchTypeData, 1, sentAt)
+	times, ok := tracker.get("/perf/watch-0", watchTypeData, 1)
+	assert.True(t, ok)
+	assert.Equal(t, sentAt, times.sentAt)
+	assert.True(t, times.ackAt.IsZero())
+	ackAt := sentAt.Add(time.Millisecond)
+	tracker.acked("/perf/watch-0", watchTypeData, 1, ackAt)
+	times, _ = tracker.get("/perf/watch-0", watchTypeData, 1)
+	assert.Equal(t, sentAt, times.sentAt)
+	assert.Equal(t, ackAt, times.ackAt)
+	_, ok = tracker.get("/perf/watch-0", watchTypeChild, 1)
+	assert.False(t, ok)
+
+	tracker.sent("/perf/watch-0", watchTypeData, 1+ackHistory, sentAt)
+	_, ok = tracker.get("/perf/watch-0", watchTypeData, 1)
+	assert.False(t, ok)
+}
+
+func TestObserveEvent(t *testing.T) {
+	unmatched := func(reason string) float64 {
+		return testutil.ToFloat64(metrics.ZkUnmatchedEvents.WithLabelValues(watchTypeData, reason))
+	}
+	noWrite, beforeAck := unmatched(unmatchedNoWrite), unmatched(unmatchedBeforeAck)
+	tracker := newAckTracker()
+	now := time.Now()
+	event := watchEvent{Type: eventNodeDataChanged, Path: "/perf/watch-0", ReceivedAt: now}
+
+	observeEvent(tracker, event, watchTypeData, 1)
+	assert.Equal(t, noWrite+1, unmatched(unmatchedNoWrite))
+
+	// the event arrived while the write was still waiting for its response
+	tracker.sent(event.Path, watchTypeData, 1, now.Add(-time.Millisecond))
+	observeEvent(tracker, event, watchTypeData, 1)
+	assert.Equal(t, beforeAck+1, unmatched(unmatchedBeforeAck))
+
+	tracker.acked(event.Path, watchTypeData, 1, now.Add(time.Millisecond))
+	observeEvent(tracker, event, watchTypeData, 1)
+	assert.Equal(t, beforeAck+2, unmatched(unmatchedBeforeAck))
+
+	tracker.sent(event.Path, watchTypeData, 2, now.Add(-2*time.Millisecond))
+	tracker.acked(event.Path, watchTypeData, 2, now.Add(-time.Millisecond))
+	observeEvent(tracker, event, watchTypeData, 2)
+	assert.Equal(t, noWrite+1, unmatched(unmatchedNoWrite))
+	assert.Equal(t, beforeAck+2, unmatched(unmatchedBeforeAck))
+}
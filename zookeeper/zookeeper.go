@@ -40,14 +40,14 @@ func Start() error {
 		return err
 	}
 
-	exists, err := client.exists(conf.ZkPath)
+	exists, err := client.exists(conf.ZkPath, false)
 
 	if err != nil {
 		return err
 	}
 
 	if exists.Error != codec.EC_OK {
-		resp, err := client.create(conf.ZkPath, []byte(""), conf.ZkPermission)
+		resp, err := client.create(conf.ZkPath, []byte(""), conf.ZkPermission, flagPersistent)
 		if err != nil {
 			logrus.Errorf("create zk path %s error %v", conf.ZkPath, err)
 			return err
@@ -59,7 +59,7 @@ func Start() error {
 		}
 	}
 
-	childrenResp, err := client.getChildren(conf.ZkPath)
+	childrenResp, err := client.getChildren(conf.ZkPath, false)
 	if err != nil {
 		logrus.Errorf("get children %s error %d", conf.ZkPath, err)
 		return err
@@ -76,7 +76,7 @@ func Start() error {
 		for _, val := range idList {
 			start := time.Now()
 			path := conf.ZkPath + "/" + val
-			resp, err := client.create(path, util.RandBytes(conf.DataSize), conf.ZkPermission)
+			resp, err := client.create(path, util.RandBytes(conf.DataSize), conf.ZkPermission, flagPersistent)
 			if err != nil {
 				metrics.SuccessCount.WithLabelValues(conf.StorageTypeZooKeeper, conf.OperationTypeInsert).Inc()
 				metrics.SuccessLatency.WithLabelValues(conf.StorageTypeZooKeeper, conf.OperationTypeInsert).Observe(float64(time.Since(start)))
@@ -89,5 +89,11 @@ func Start() error {
 		}
 	}
 	defer client.close()
+	switch conf.ZkMode {
+	case conf.ZkModeWatch:
+		return startWatch(client)
+	case conf.ZkModeChurn:
+		return startChurn(client)
+	}
 	return nil
 }